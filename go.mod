@@ -20,6 +20,7 @@ require (
 	k8s.io/cli-runtime v0.0.0-20190531135611-d60f41fb4dc3
 	k8s.io/client-go v11.0.0+incompatible
 	k8s.io/kubernetes v1.14.3
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace (
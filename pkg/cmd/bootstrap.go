@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	clientappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/apis/core/v1/helper"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// bootstrapLong defines long description
+	bootstrapLong = templates.LongDesc(`
+		Estimate images to be pulled by a fresh node in a node pool.
+
+		Nodes in the pool are selected by --pool instead of --selector, and
+		other node filters apply to them.
+
+		Images of DaemonSets which match the pool (and pending pods targeted
+		at the pool with --include-pending) are counted. Labels of existing
+		nodes in the pool are used to match nodeSelector and required node
+		affinity. Pending pods without them are not counted because they are
+		not targeted at any pool. Size of image is taken from other nodes, so
+		it is "N/A" if no nodes have the image. Total is not printed with
+		-o csv or -o tsv.
+	`)
+
+	// bootstrapExample defines command examples
+	bootstrapExample = templates.Examples(`
+		# Show images to be pulled by a new node in the pool.
+		kubectl dfi bootstrap-cost --pool cloud.google.com/gke-nodepool=pool-a
+
+		# Include pending pods and estimate pull time with 50MiB/s bandwidth.
+		kubectl dfi bootstrap-cost --pool pool=a --include-pending --bandwidth 50Mi
+	`)
+)
+
+// BootstrapOptions is struct of bootstrap-cost options
+type BootstrapOptions struct {
+	*DfiOptions
+
+	// bootstrap-cost options
+	pool           string
+	includePending bool
+	bandwidth      string
+
+	// k8s clients
+	podClient       clientv1.PodInterface
+	daemonSetClient clientappsv1.DaemonSetInterface
+}
+
+// bootstrapImage is an image to be pulled by a fresh node
+type bootstrapImage struct {
+	name    string
+	size    int64
+	sources []string
+}
+
+// NewBootstrapOptions is an instance of BootstrapOptions
+func NewBootstrapOptions(dfi *DfiOptions) *BootstrapOptions {
+	return &BootstrapOptions{
+		DfiOptions:     dfi,
+		pool:           "",
+		includePending: false,
+		bandwidth:      "100Mi",
+	}
+}
+
+// NewCmdBootstrap is a cobra command wrapping
+func NewCmdBootstrap(dfi *DfiOptions) *cobra.Command {
+	o := NewBootstrapOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "bootstrap-cost",
+		Short:   "Estimate images to be pulled by a fresh node in a node pool.",
+		Long:    bootstrapLong,
+		Example: bootstrapExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.includePending, "include-pending", "", o.includePending, `Include images of pending pods targeted at the pool.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.pool, "pool", "", o.pool, `Selector (label query) of nodes in the pool.`)
+	cmd.Flags().StringVarP(&o.bandwidth, "bandwidth", "", o.bandwidth, `Bandwidth (bytes per second) to estimate pull time.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *BootstrapOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	o.podClient = o.clientset.CoreV1().Pods(metav1.NamespaceAll)
+	o.daemonSetClient = o.clientset.AppsV1().DaemonSets(metav1.NamespaceAll)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *BootstrapOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if o.pool == "" {
+		return fmt.Errorf("--pool is required")
+	}
+
+	if _, err := labels.Parse(o.pool); err != nil {
+		return fmt.Errorf("invalid pool: %v", err)
+	}
+
+	if _, err := o.getBandwidth(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run printing images to be pulled by a fresh node
+func (o *BootstrapOptions) Run(args []string) error {
+
+	// get nodes at once to know image sizes and nodes in the pool
+	// --pool is used instead of --selector to select nodes in the pool.
+	nodes, err := o.getNodesWithoutSelector()
+	if err != nil {
+		return err
+	}
+	pool, _ := labels.Parse(o.pool)
+	poolNodes := []v1.Node{}
+	for _, node := range nodes {
+		if pool.Matches(labels.Set(node.ObjectMeta.Labels)) {
+			poolNodes = append(poolNodes, node)
+		}
+	}
+	if len(poolNodes) == 0 {
+		return fmt.Errorf("no nodes found in pool: %s", o.pool)
+	}
+
+	images, err := o.getBootstrapImages(poolNodes, getKnownImageSizes(nodes))
+	if err != nil {
+		return err
+	}
+
+	bandwidth, _ := o.getBandwidth()
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+		{Name: "SOURCE", Type: table.String},
+	})
+
+	var total int64
+	for _, i := range images {
+		total += i.size
+
+		row := []table.Cell{o.bytesCell(i.size), o.imageNameCell(i.name), {Text: strings.Join(i.sources, ",")}}
+		o.table.AddCells(row)
+	}
+
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+
+	if o.isRecordOutput() {
+		return nil
+	}
+
+	fmt.Fprintf(
+		o.Out,
+		"\nTotal: %s (%d images), estimated pull time: %s\n",
+		o.toUnit(total),
+		len(images),
+		getPullTime(total, bandwidth),
+	)
+
+	return nil
+}
+
+// getBootstrapImages returns images of daemonsets and pending pods for the pool
+func (o *BootstrapOptions) getBootstrapImages(poolNodes []v1.Node, sizes map[string]int64) ([]bootstrapImage, error) {
+
+	images := map[string]*bootstrapImage{}
+	add := func(spec v1.PodSpec, source string, targeted bool) {
+		if !matchesAnyNode(spec, poolNodes, targeted) {
+			return
+		}
+		for _, name := range util.GetPodImages(v1.Pod{Spec: spec}) {
+			n := util.NormalizeImageName(name)
+			if images[n] == nil {
+				images[n] = &bootstrapImage{name: name, size: sizes[n]}
+			}
+			images[n].sources = append(images[n].sources, source)
+		}
+	}
+
+	dsl, dslerr := o.daemonSetClient.List(metav1.ListOptions{})
+	if dslerr != nil {
+		return nil, fmt.Errorf("failed to get daemonsets: %v", dslerr)
+	}
+	for _, ds := range dsl.Items {
+		add(ds.Spec.Template.Spec, "DaemonSet/"+ds.ObjectMeta.Namespace+"/"+ds.ObjectMeta.Name, false)
+	}
+
+	if o.includePending {
+		pl, plerr := o.podClient.List(metav1.ListOptions{})
+		if plerr != nil {
+			return nil, fmt.Errorf("failed to get pods: %v", plerr)
+		}
+		for _, pod := range pl.Items {
+			if pod.Status.Phase != v1.PodPending || pod.Spec.NodeName != "" {
+				continue
+			}
+			add(pod.Spec, "Pod/"+pod.ObjectMeta.Namespace+"/"+pod.ObjectMeta.Name, true)
+		}
+	}
+
+	ret := []bootstrapImage{}
+	for _, i := range images {
+		ret = append(ret, *i)
+	}
+
+	// bigger image first
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].size != ret[j].size {
+			return ret[i].size > ret[j].size
+		}
+		return ret[i].name < ret[j].name
+	})
+
+	return ret, nil
+}
+
+// getBandwidth returns bandwidth in bytes per second
+func (o *BootstrapOptions) getBandwidth() (int64, error) {
+
+	q, err := resource.ParseQuantity(o.bandwidth)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth: %s", o.bandwidth)
+	}
+
+	b := q.Value()
+	if b <= 0 {
+		return 0, fmt.Errorf("bandwidth must be positive: %s", o.bandwidth)
+	}
+
+	return b, nil
+}
+
+// matchesAnyNode reports whether pods of spec can run on any of nodes
+// Both nodeSelector and required node affinity must match labels of a node.
+// With targeted, spec which has neither of them does not match because it
+// matches all nodes and is not targeted at the nodes.
+func matchesAnyNode(spec v1.PodSpec, nodes []v1.Node, targeted bool) bool {
+
+	terms := getRequiredNodeSelectorTerms(spec)
+	if targeted && len(spec.NodeSelector) == 0 && len(terms) == 0 {
+		return false
+	}
+
+	selector := labels.SelectorFromSet(spec.NodeSelector)
+	for _, node := range nodes {
+		l := labels.Set(node.ObjectMeta.Labels)
+		if !selector.Matches(l) {
+			continue
+		}
+		// fields (e.g. metadata.name) of existing nodes never match a fresh node
+		if len(terms) > 0 && !helper.MatchNodeSelectorTerms(terms, l, fields.Set{}) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// getRequiredNodeSelectorTerms returns terms of required node affinity
+func getRequiredNodeSelectorTerms(spec v1.PodSpec) []v1.NodeSelectorTerm {
+
+	a := spec.Affinity
+	if a == nil || a.NodeAffinity == nil || a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+	return a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+}
+
+// getPullTime returns estimated time to pull bytes
+func getPullTime(bytes, bandwidth int64) time.Duration {
+	return time.Duration(float64(bytes) / float64(bandwidth) * float64(time.Second)).Round(time.Second)
+}
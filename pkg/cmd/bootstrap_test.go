@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for bootstrap-cost
+var (
+	testOtherPoolDaemonSet = appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					NodeSelector: map[string]string{"hostname": "node2"},
+					Containers:   []v1.Container{{Name: "c1", Image: "image3"}},
+				},
+			},
+		},
+	}
+
+	testPendingPod = v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{"hostname": "node1"},
+			Containers:   []v1.Container{{Name: "c1", Image: "image1"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodPending},
+	}
+
+	testUntargetedPendingPod = v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "untargeted", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "c1", Image: "image3"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodPending},
+	}
+
+	testNodeAffinity = func(key, value string) *v1.Affinity {
+		return &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{Key: key, Operator: v1.NodeSelectorOpIn, Values: []string{value}},
+						},
+					}},
+				},
+			},
+		}
+	}
+)
+
+func TestNewBootstrapOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &BootstrapOptions{
+		DfiOptions:     dfi,
+		pool:           "",
+		includePending: false,
+		bandwidth:      "100Mi",
+	}
+
+	actual := NewBootstrapOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestBootstrapValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		pool        string
+		bandwidth   string
+		expected    string
+	}{
+		{"valid", "pool=a", "10Mi", ""},
+		{"no pool", "", "10Mi", "--pool is required"},
+		{"invalid bandwidth", "pool=a", "fast", "invalid bandwidth: fast"},
+		{"zero bandwidth", "pool=a", "0", "bandwidth must be positive: 0"},
+		{"invalid pool", "pool in a", "10Mi", "invalid pool: unable to parse requirement: found 'a' expected: '('"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &BootstrapOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				pool:       test.pool,
+				bandwidth:  test.bandwidth,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestBootstrapRun(t *testing.T) {
+
+	var tests = []struct {
+		description    string
+		pool           string
+		includePending bool
+		output         string
+		expected       []string
+		expectedErr    string
+	}{
+		{
+			"daemonsets",
+			"hostname=node1",
+			false,
+			"",
+			[]string{
+				"IMAGE SIZE   IMAGE NAME   SOURCE",
+				"     1000B   image2       DaemonSet/kube-system/logger",
+				"",
+				"Total: 1000B (1 images), estimated pull time: 1s",
+				"",
+			},
+			"",
+		},
+		{
+			"include pending pods",
+			"hostname=node1",
+			true,
+			"",
+			[]string{
+				"IMAGE SIZE   IMAGE NAME   SOURCE",
+				"     2000B   image1       Pod/default/pending",
+				"     1000B   image2       DaemonSet/kube-system/logger",
+				"",
+				"Total: 3000B (2 images), estimated pull time: 3s",
+				"",
+			},
+			"",
+		},
+		{
+			"csv",
+			"hostname=node1",
+			true,
+			"csv",
+			[]string{
+				"IMAGE SIZE,IMAGE NAME,SOURCE",
+				"2000,image1,Pod/default/pending",
+				"1000,image2,DaemonSet/kube-system/logger",
+				"",
+			},
+			"",
+		},
+		{
+			"empty pool",
+			"hostname=node9",
+			false,
+			"",
+			[]string{},
+			"no nodes found in pool: hostname=node9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(
+				&testNodes[0], &testNodes[1], &testDaemonSet, &testOtherPoolDaemonSet, &testPendingPod, &testUntargetedPendingPod, &testPods[0],
+			)
+
+			buffer := &bytes.Buffer{}
+			o := &BootstrapOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams: genericclioptions.IOStreams{Out: buffer},
+					bytes:     true,
+					nocolor:   true,
+					output:    test.output,
+					// --pool is used instead of --selector
+					labelSelector: "hostname=node2",
+					table:         table.NewOutputTable(buffer),
+					nodeClient:    fakeClient.CoreV1().Nodes(),
+				},
+				pool:            test.pool,
+				includePending:  test.includePending,
+				bandwidth:       "1k",
+				podClient:       fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+				daemonSetClient: fakeClient.AppsV1().DaemonSets(metav1.NamespaceAll),
+			}
+
+			err := o.Run([]string{})
+			if (err == nil && test.expectedErr != "") || (err != nil && err.Error() != test.expectedErr) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestMatchesAnyNode(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		spec        v1.PodSpec
+		targeted    bool
+		expected    bool
+	}{
+		{"empty selector", v1.PodSpec{}, false, true},
+		{"empty selector of targeted", v1.PodSpec{}, true, false},
+		{"selector", v1.PodSpec{NodeSelector: map[string]string{"hostname": "node1"}}, true, true},
+		{"selector of other nodes", v1.PodSpec{NodeSelector: map[string]string{"hostname": "node2"}}, true, false},
+		{"affinity", v1.PodSpec{Affinity: testNodeAffinity("hostname", "node1")}, true, true},
+		{"affinity of other nodes", v1.PodSpec{Affinity: testNodeAffinity("hostname", "node2")}, true, false},
+		{
+			"selector and affinity of other nodes",
+			v1.PodSpec{NodeSelector: map[string]string{"hostname": "node1"}, Affinity: testNodeAffinity("hostname", "node2")},
+			false,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := matchesAnyNode(test.spec, []v1.Node{testNodes[0]}, test.targeted)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%t) differ (got: %t)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPullTime(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		bytes       int64
+		bandwidth   int64
+		expected    time.Duration
+	}{
+		{"zero", 0, 1000, 0},
+		{"1 second", 1000, 1000, time.Second},
+		{"round", 1600, 1000, 2 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getPullTime(test.bytes, test.bandwidth)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
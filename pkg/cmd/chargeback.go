@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/constants"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// chargebackLong defines long description
+	chargebackLong = templates.LongDesc(`
+		Attribute disk usage of images on Kubernetes nodes to namespaces.
+
+		Images shared by some namespaces are split equally or by pod count.
+		Images not used by any pods are reported as "<none>".
+	`)
+
+	// chargebackExample defines command examples
+	chargebackExample = templates.Examples(`
+		# Show image usage per namespace.
+		kubectl dfi chargeback
+
+		# Split shared images by pod count and show cost.
+		kubectl dfi chargeback --split pods --price-per-gb 0.1
+
+		# Export as csv.
+		kubectl dfi chargeback -o csv
+
+		# Print as markdown table.
+		kubectl dfi chargeback -o markdown
+	`)
+
+	// chargebackSplits defines valid values for --split
+	chargebackSplits = []string{"equal", "pods"}
+
+	// chargebackOutputs defines valid values for --output
+	chargebackOutputs = append([]string{"json"}, tableOutputs...)
+)
+
+// ChargebackOptions is struct of chargeback options
+type ChargebackOptions struct {
+	*DfiOptions
+
+	// chargeback options
+	split      string
+	pricePerGB float64
+
+	// k8s pod client
+	podClient clientv1.PodInterface
+}
+
+// namespaceUsage is image usage attributed to a namespace
+type namespaceUsage struct {
+	Namespace string  `json:"namespace"`
+	Bytes     int64   `json:"bytes"`
+	Percent   float64 `json:"percent"`
+	Cost      float64 `json:"cost"`
+}
+
+// NewChargebackOptions is an instance of ChargebackOptions
+func NewChargebackOptions(dfi *DfiOptions) *ChargebackOptions {
+	return &ChargebackOptions{
+		DfiOptions: dfi,
+		split:      "equal",
+		pricePerGB: 0,
+	}
+}
+
+// NewCmdChargeback is a cobra command wrapping
+func NewCmdChargeback(dfi *DfiOptions) *cobra.Command {
+	o := NewChargebackOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "chargeback",
+		Short:   "Attribute disk usage of images to namespaces.",
+		Long:    chargebackLong,
+		Example: chargebackExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// float64 options
+	cmd.Flags().Float64VarP(&o.pricePerGB, "price-per-gb", "", o.pricePerGB, `Price per GB(1000^3 bytes) to calculate cost of namespace.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.split, "split", "", o.split, `How to split shared images. One of `+strings.Join(chargebackSplits, "|")+`.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(chargebackOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *ChargebackOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	o.podClient = o.clientset.CoreV1().Pods(metav1.NamespaceAll)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *ChargebackOptions) Validate() error {
+
+	// --output of chargeback accepts json
+	if err := o.validateCommon(); err != nil {
+		return err
+	}
+
+	if !containsString(chargebackSplits, o.split) {
+		return fmt.Errorf("invalid split: %s (valid values: %s)", o.split, strings.Join(chargebackSplits, ", "))
+	}
+
+	if o.output != "" && !containsString(chargebackOutputs, o.output) {
+		return fmt.Errorf("invalid output: %s (valid values: %s)", o.output, strings.Join(chargebackOutputs, ", "))
+	}
+
+	if o.pricePerGB < 0 {
+		return fmt.Errorf("can not set negative price: %v", o.pricePerGB)
+	}
+
+	return nil
+}
+
+// Run printing image usage of namespaces
+func (o *ChargebackOptions) Run(args []string) error {
+
+	// get nodes
+	nodes, err := o.getNodes([]string{})
+	if err != nil {
+		return err
+	}
+
+	// get pods
+	pl, plerr := o.podClient.List(metav1.ListOptions{})
+	if plerr != nil {
+		return fmt.Errorf("failed to get pods: %v", plerr)
+	}
+
+	usages := o.chargeback(nodes, pl.Items)
+
+	if o.output == "json" {
+		return o.printChargebackJSON(usages)
+	}
+
+	return o.printChargebackTable(usages)
+}
+
+// chargeback attributes image usage on nodes to namespaces
+func (o *ChargebackOptions) chargeback(nodes []v1.Node, pods []v1.Pod) []namespaceUsage {
+
+	// pods on each node
+	podsOnNode := map[string][]v1.Pod{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		podsOnNode[pod.Spec.NodeName] = append(podsOnNode[pod.Spec.NodeName], pod)
+	}
+
+	// namespace -> bytes
+	shares := map[string]float64{}
+	var total float64
+
+	// node loop
+	for _, node := range nodes {
+
+		// image index -> namespace -> pod count
+		users := o.getImageUsers(node.Status.Images, podsOnNode[node.ObjectMeta.Name])
+
+		for i, image := range node.Status.Images {
+			size := float64(image.SizeBytes)
+			total += size
+
+			nsPods := users[i]
+			if len(nsPods) == 0 {
+				shares["<none>"] += size
+				continue
+			}
+
+			var podCount int
+			for _, c := range nsPods {
+				podCount += c
+			}
+
+			for ns, c := range nsPods {
+				if o.split == "pods" {
+					shares[ns] += size * float64(c) / float64(podCount)
+				} else {
+					shares[ns] += size / float64(len(nsPods))
+				}
+			}
+		}
+	}
+
+	usages := []namespaceUsage{}
+	for ns, b := range shares {
+		u := namespaceUsage{
+			Namespace: ns,
+			Bytes:     int64(b + 0.5),
+			Cost:      b / constants.UnitGigaBytes * o.pricePerGB,
+		}
+		if total > 0 {
+			u.Percent = b * 100 / total
+		}
+		usages = append(usages, u)
+	}
+
+	// bigger usage first
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Bytes != usages[j].Bytes {
+			return usages[i].Bytes > usages[j].Bytes
+		}
+		return usages[i].Namespace < usages[j].Namespace
+	})
+
+	return usages
+}
+
+// getImageUsers returns pod count of each namespace for each image on node
+func (o *ChargebackOptions) getImageUsers(images []v1.ContainerImage, pods []v1.Pod) map[int]map[string]int {
+
+	// normalized image name -> image index
+	index := map[string]int{}
+	for i, image := range images {
+		for _, name := range image.Names {
+			index[util.NormalizeImageName(name)] = i
+		}
+	}
+
+	users := map[int]map[string]int{}
+	for _, pod := range pods {
+
+		// count a pod once for each image
+		used := map[int]bool{}
+		ids := util.GetPodImageIDs(pod)
+		for _, name := range util.GetPodImages(pod) {
+			i, found := index[util.NormalizeImageName(name)]
+			if !found && ids.Get(name) != "" {
+				i, found = index[util.NormalizeImageName(ids.Get(name))]
+			}
+			if found {
+				used[i] = true
+			}
+		}
+
+		for i := range used {
+			if users[i] == nil {
+				users[i] = map[string]int{}
+			}
+			users[i][pod.ObjectMeta.Namespace]++
+		}
+	}
+
+	return users
+}
+
+// printChargebackTable prints usages as table
+func (o *ChargebackOptions) printChargebackTable(usages []namespaceUsage) error {
+
+	// set printer header
+	columns := []table.Column{
+		{Name: "NAMESPACE", Type: table.String},
+		{Name: "IMAGE USED", Type: table.Bytes},
+		{Name: "%TOTAL", Type: table.Percent},
+	}
+	if o.pricePerGB > 0 {
+		columns = append(columns, table.Column{Name: "COST", Type: table.Number})
+	}
+	o.setTableFormat()
+	o.table.AddColumns(columns)
+
+	for _, u := range usages {
+		row := []table.Cell{
+			{Text: u.Namespace},
+			o.bytesCell(u.Bytes),
+			{
+				Text:  strconv.FormatFloat(u.Percent, 'f', 1, 64) + "%",
+				Value: int64(u.Percent),
+				Raw:   strconv.FormatFloat(u.Percent, 'f', 2, 64),
+			},
+		}
+		if o.pricePerGB > 0 {
+			cost := strconv.FormatFloat(u.Cost, 'f', 2, 64)
+			row = append(row, table.Cell{Text: cost, Value: int64(u.Cost), Raw: cost})
+		}
+		o.table.AddCells(row)
+	}
+
+	return o.table.Print()
+}
+
+// printChargebackJSON prints usages as json
+func (o *ChargebackOptions) printChargebackJSON(usages []namespaceUsage) error {
+
+	e := json.NewEncoder(o.Out)
+	e.SetIndent("", "  ")
+
+	return e.Encode(usages)
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for chargeback
+var (
+	testChargebackNode = v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node3"},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{Names: []string{"unused"}, SizeBytes: 4000},
+			},
+		},
+	}
+
+	testChargebackPods = []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod4", Namespace: "kube-system"},
+			Spec: v1.PodSpec{
+				NodeName:   "node1",
+				Containers: []v1.Container{{Name: "c1", Image: "image1"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod5", Namespace: "kube-system"},
+			Spec: v1.PodSpec{
+				NodeName:   "node1",
+				Containers: []v1.Container{{Name: "c1", Image: "image1"}},
+			},
+		},
+	}
+)
+
+func TestNewChargebackOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &ChargebackOptions{
+		DfiOptions: dfi,
+		split:      "equal",
+		pricePerGB: 0,
+	}
+
+	actual := NewChargebackOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestChargebackValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		split       string
+		output      string
+		price       float64
+		expected    string
+	}{
+		{"valid", "pods", "csv", 0.1, ""},
+		{"invalid split", "foo", "", 0, "invalid split: foo (valid values: equal, pods)"},
+		{"json", "equal", "json", 0, ""},
+		{"invalid output", "equal", "yaml", 0, "invalid output: yaml (valid values: json, csv, tsv, markdown)"},
+		{"negative price", "equal", "", -1, "can not set negative price: -1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &ChargebackOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50, output: test.output},
+				split:      test.split,
+				pricePerGB: test.price,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestChargebackRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		split       string
+		output      string
+		expected    []string
+	}{
+		{
+			"equal split",
+			"equal",
+			"",
+			[]string{
+				"NAMESPACE     IMAGE USED   %TOTAL",
+				"<none>             4000B    57.1%",
+				"kube-system        2500B    35.7%",
+				"default             500B     7.1%",
+				"",
+			},
+		},
+		{
+			"split by pods",
+			"pods",
+			"",
+			[]string{
+				"NAMESPACE     IMAGE USED   %TOTAL",
+				"<none>             4000B    57.1%",
+				"kube-system        2667B    38.1%",
+				"default             333B     4.8%",
+				"",
+			},
+		},
+		{
+			"csv",
+			"equal",
+			"csv",
+			[]string{
+				"NAMESPACE,IMAGE USED,%TOTAL",
+				"<none>,4000,57.14",
+				"kube-system,2500,35.71",
+				"default,500,7.14",
+				"",
+			},
+		},
+		{
+			"tsv",
+			"equal",
+			"tsv",
+			[]string{
+				"NAMESPACE\tIMAGE USED\t%TOTAL",
+				"<none>\t4000\t57.14",
+				"kube-system\t2500\t35.71",
+				"default\t500\t7.14",
+				"",
+			},
+		},
+		{
+			"markdown",
+			"equal",
+			"markdown",
+			[]string{
+				"| NAMESPACE | IMAGE USED | %TOTAL |",
+				"| --- | ---: | ---: |",
+				"| <none> | 4000B | 57.1% |",
+				"| kube-system | 2500B | 35.7% |",
+				"| default | 500B | 7.1% |",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(
+				&testNodes[0], &testNodes[1], &testChargebackNode,
+				&testPods[0], &testPods[1], &testPods[2], &testChargebackPods[0], &testChargebackPods[1],
+			)
+
+			buffer := &bytes.Buffer{}
+			o := &ChargebackOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams:  genericclioptions.IOStreams{Out: buffer},
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				split:     test.split,
+				podClient: fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestChargebackJSON(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	o := &ChargebackOptions{
+		DfiOptions: &DfiOptions{
+			IOStreams: genericclioptions.IOStreams{Out: buffer},
+		},
+		split:      "equal",
+		pricePerGB: 1,
+	}
+
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: v1.NodeStatus{
+				Images: []v1.ContainerImage{
+					{Names: []string{"image1"}, SizeBytes: 2000000000},
+				},
+			},
+		},
+	}
+
+	usages := o.chargeback(nodes, []v1.Pod{testChargebackPods[0]})
+	if err := o.printChargebackJSON(usages); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := []namespaceUsage{
+		{Namespace: "kube-system", Bytes: 2000000000, Percent: 100, Cost: 2},
+	}
+
+	actual := []namespaceUsage{}
+	if err := json.Unmarshal(buffer.Bytes(), &actual); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// compareLong defines long description
+	compareLong = templates.LongDesc(`
+		Compare images on two nodes or two groups of nodes.
+
+		Images only on A, only on B and on both are printed with total size of
+		each set. With --selector-a and --selector-b, images on all nodes which
+		match with the selector (and node filters other than --selector) are
+		compared. Totals of sets are not printed with -o csv or -o tsv.
+	`)
+
+	// compareExample defines command examples
+	compareExample = templates.Examples(`
+		# Compare images on two nodes.
+		kubectl dfi compare node1 node2
+
+		# Compare images on two node pools.
+		kubectl dfi compare --selector-a pool=a --selector-b pool=b
+
+		# Export images of sets as csv.
+		kubectl dfi compare node1 node2 -o csv
+	`)
+)
+
+// CompareOptions is struct of compare options
+type CompareOptions struct {
+	*DfiOptions
+
+	// compare options
+	selectorA string
+	selectorB string
+}
+
+// imageSet is images in a set of comparison
+type imageSet struct {
+	name   string
+	images []v1.ContainerImage
+}
+
+// NewCompareOptions is an instance of CompareOptions
+func NewCompareOptions(dfi *DfiOptions) *CompareOptions {
+	return &CompareOptions{
+		DfiOptions: dfi,
+		selectorA:  "",
+		selectorB:  "",
+	}
+}
+
+// NewCmdCompare is a cobra command wrapping
+func NewCmdCompare(dfi *DfiOptions) *cobra.Command {
+	o := NewCompareOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "compare [NODE_A NODE_B]",
+		Short:   "Compare images on two nodes.",
+		Long:    compareLong,
+		Example: compareExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// string option
+	cmd.Flags().StringVarP(&o.selectorA, "selector-a", "", o.selectorA, `Label selector of nodes of A.`)
+	cmd.Flags().StringVarP(&o.selectorB, "selector-b", "", o.selectorB, `Label selector of nodes of B.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *CompareOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *CompareOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if (o.selectorA == "") != (o.selectorB == "") {
+		return fmt.Errorf("--selector-a and --selector-b must be specified together")
+	}
+
+	return nil
+}
+
+// Run printing images only on A, only on B and on both
+func (o *CompareOptions) Run(args []string) error {
+
+	a, b, err := o.getCompareNodes(args)
+	if err != nil {
+		return err
+	}
+
+	onlyA, onlyB, both := compareImages(getImageSet(a), getImageSet(b))
+	sets := []imageSet{
+		{name: "only-a", images: onlyA},
+		{name: "only-b", images: onlyB},
+		{name: "both", images: both},
+	}
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: "SET", Type: table.String},
+		{Name: "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+	})
+
+	for _, s := range sets {
+		for _, image := range s.images {
+			name := util.GetImageName(image)
+			o.table.AddCells([]table.Cell{{Text: s.name}, o.bytesCell(image.SizeBytes), o.imageNameCell(name)})
+		}
+	}
+
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+
+	// totals of sets
+	if o.isRecordOutput() {
+		return nil
+	}
+	fmt.Fprintln(o.table.Output)
+	t := o.newSectionTable()
+	t.AddColumns([]table.Column{
+		{Name: "SET", Type: table.String},
+		{Name: "IMAGES", Type: table.Count},
+		{Name: "TOTAL", Type: table.Bytes},
+	})
+	for _, s := range sets {
+		total, count := util.GetImageUsage(s.images)
+		t.AddCells([]table.Cell{{Text: s.name}, countCell(count), o.bytesOrZeroCell(total)})
+	}
+	return t.Print()
+}
+
+// getCompareNodes returns nodes of A and B
+func (o *CompareOptions) getCompareNodes(args []string) ([]v1.Node, []v1.Node, error) {
+
+	if o.selectorA != "" {
+		if len(args) > 0 {
+			return nil, nil, fmt.Errorf("can not specify nodes with --selector-a and --selector-b")
+		}
+
+		// selectors of sets are used instead of --selector
+		a, err := o.getNodesWithSelector(o.selectorA)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := o.getNodesWithSelector(o.selectorB)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(a) == 0 || len(b) == 0 {
+			return nil, nil, fmt.Errorf("no nodes found (a:%d b:%d)", len(a), len(b))
+		}
+
+		return a, b, nil
+	}
+
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("two nodes are required (got: %d)", len(args))
+	}
+
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nodes) != 2 {
+		return nil, nil, fmt.Errorf("two nodes are required (got: %d)", len(nodes))
+	}
+
+	return nodes[:1], nodes[1:], nil
+}
+
+// getImageSet returns unique images on nodes
+// The biggest size is used if an image has different sizes on nodes.
+func getImageSet(nodes []v1.Node) []v1.ContainerImage {
+
+	images := []v1.ContainerImage{}
+	index := imageIndex{}
+	for _, node := range nodes {
+		for _, image := range node.Status.Images {
+			i := index.find(image)
+			if i < 0 {
+				index.add(image, len(images))
+				images = append(images, image)
+				continue
+			}
+			if image.SizeBytes > images[i].SizeBytes {
+				images[i].SizeBytes = image.SizeBytes
+			}
+		}
+	}
+
+	return images
+}
+
+// compareImages returns images only in a, only in b and in both
+// Images are sorted by size in descending order.
+func compareImages(a, b []v1.ContainerImage) ([]v1.ContainerImage, []v1.ContainerImage, []v1.ContainerImage) {
+
+	indexA, indexB := newImageIndex(a), newImageIndex(b)
+
+	onlyA, onlyB, both := []v1.ContainerImage{}, []v1.ContainerImage{}, []v1.ContainerImage{}
+	for _, image := range a {
+		if indexB.find(image) < 0 {
+			onlyA = append(onlyA, image)
+		} else {
+			both = append(both, image)
+		}
+	}
+	for _, image := range b {
+		if indexA.find(image) < 0 {
+			onlyB = append(onlyB, image)
+		}
+	}
+
+	for _, images := range [][]v1.ContainerImage{onlyA, onlyB, both} {
+		sort.SliceStable(images, func(i, j int) bool {
+			return images[i].SizeBytes > images[j].SizeBytes
+		})
+	}
+
+	return onlyA, onlyB, both
+}
+
+// imageIndex is index of images by normalized names
+// Names are normalized once to find images with any name of an image.
+type imageIndex map[string]int
+
+// newImageIndex returns index of images
+func newImageIndex(images []v1.ContainerImage) imageIndex {
+
+	index := imageIndex{}
+	for i, image := range images {
+		index.add(image, i)
+	}
+	return index
+}
+
+// add adds names of image at i
+// The first image is kept for names which are already added.
+func (x imageIndex) add(image v1.ContainerImage, i int) {
+
+	for _, name := range image.Names {
+		n := util.NormalizeImageName(name)
+		if _, ok := x[n]; !ok {
+			x[n] = i
+		}
+	}
+}
+
+// find returns index of image which has any name of image
+// It returns -1 if image is not found.
+func (x imageIndex) find(image v1.ContainerImage) int {
+
+	for _, name := range image.Names {
+		if i, ok := x[util.NormalizeImageName(name)]; ok {
+			return i
+		}
+	}
+	return -1
+}
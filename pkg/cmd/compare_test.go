@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for compare
+var testCompareNodes = []v1.Node{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "a1", Labels: map[string]string{"pool": "a"}},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{Names: []string{"image-common"}, SizeBytes: 1000},
+				{Names: []string{"image-a"}, SizeBytes: 3000},
+				{Names: []string{"image-big"}, SizeBytes: 5000},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "a2", Labels: map[string]string{"pool": "a"}},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{Names: []string{"docker.io/library/image-common:latest"}, SizeBytes: 1200},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "b1", Labels: map[string]string{"pool": "b"}},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{Names: []string{"image-common"}, SizeBytes: 1000},
+				{Names: []string{"image-b"}, SizeBytes: 2000},
+			},
+		},
+	},
+}
+
+func TestNewCompareOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &CompareOptions{
+		DfiOptions: dfi,
+		selectorA:  "",
+		selectorB:  "",
+	}
+
+	actual := NewCompareOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestCompareValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		selectorA   string
+		selectorB   string
+		expected    string
+	}{
+		{"nodes", "", "", ""},
+		{"selectors", "pool=a", "pool=b", ""},
+		{"selector a only", "pool=a", "", "--selector-a and --selector-b must be specified together"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &CompareOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				selectorA:  test.selectorA,
+				selectorB:  test.selectorB,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestCompareRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		args        []string
+		selectorA   string
+		selectorB   string
+		output      string
+		expected    []string
+	}{
+		{
+			"nodes",
+			[]string{"a1", "b1"},
+			"",
+			"",
+			"",
+			[]string{
+				"SET      IMAGE SIZE   IMAGE NAME",
+				"only-a        5000B   image-big",
+				"only-a        3000B   image-a",
+				"only-b        2000B   image-b",
+				"both          1000B   image-common",
+				"",
+				"SET      IMAGES   TOTAL",
+				"only-a        2   8000B",
+				"only-b        1   2000B",
+				"both          1   1000B",
+				"",
+			},
+		},
+		{
+			"selectors",
+			[]string{},
+			"pool=b",
+			"pool=a",
+			"",
+			[]string{
+				"SET      IMAGE SIZE   IMAGE NAME",
+				"only-a        2000B   image-b",
+				"only-b        5000B   image-big",
+				"only-b        3000B   image-a",
+				"both          1000B   image-common",
+				"",
+				"SET      IMAGES   TOTAL",
+				"only-a        1   2000B",
+				"only-b        2   8000B",
+				"both          1   1000B",
+				"",
+			},
+		},
+		{
+			"same nodes",
+			[]string{"b1", "b1"},
+			"",
+			"",
+			"",
+			[]string{
+				"SET    IMAGE SIZE   IMAGE NAME",
+				"both        2000B   image-b",
+				"both        1000B   image-common",
+				"",
+				"SET      IMAGES   TOTAL",
+				"only-a        0      0B",
+				"only-b        0      0B",
+				"both          2   3000B",
+				"",
+			},
+		},
+		{
+			"csv",
+			[]string{"a1", "b1"},
+			"",
+			"",
+			"csv",
+			[]string{
+				"SET,IMAGE SIZE,IMAGE NAME",
+				"only-a,5000,image-big",
+				"only-a,3000,image-a",
+				"only-b,2000,image-b",
+				"both,1000,image-common",
+				"",
+			},
+		},
+		{
+			"markdown",
+			[]string{"a1", "b1"},
+			"",
+			"",
+			"markdown",
+			[]string{
+				"| SET | IMAGE SIZE | IMAGE NAME |",
+				"| --- | ---: | --- |",
+				"| only-a | 5000B | image-big |",
+				"| only-a | 3000B | image-a |",
+				"| only-b | 2000B | image-b |",
+				"| both | 1000B | image-common |",
+				"",
+				"| SET | IMAGES | TOTAL |",
+				"| --- | ---: | ---: |",
+				"| only-a | 2 | 8000B |",
+				"| only-b | 1 | 2000B |",
+				"| both | 1 | 1000B |",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testCompareNodes[0], &testCompareNodes[1], &testCompareNodes[2])
+
+			buffer := &bytes.Buffer{}
+			o := &CompareOptions{
+				DfiOptions: &DfiOptions{
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				selectorA: test.selectorA,
+				selectorB: test.selectorB,
+			}
+
+			if err := o.Run(test.args); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestGetCompareNodes(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		args        []string
+		selectorA   string
+		selectorB   string
+		readyOnly   bool
+		expected    string
+	}{
+		{"one node", []string{"a1"}, "", "", false, "two nodes are required (got: 1)"},
+		{"nodes and selectors", []string{"a1", "b1"}, "pool=a", "pool=b", false, "can not specify nodes with --selector-a and --selector-b"},
+		{"no nodes", []string{}, "pool=a", "pool=c", false, "no nodes found (a:2 b:0)"},
+		{"node filters", []string{}, "pool=a", "pool=b", true, "no nodes found (a:0 b:0)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testCompareNodes[0], &testCompareNodes[1], &testCompareNodes[2])
+
+			o := &CompareOptions{
+				DfiOptions: &DfiOptions{labelSelector: "pool", readyOnly: test.readyOnly, nodeClient: fakeClient.CoreV1().Nodes()},
+				selectorA:  test.selectorA,
+				selectorB:  test.selectorB,
+			}
+
+			_, _, actual := o.getCompareNodes(test.args)
+			if o.labelSelector != "pool" {
+				t.Errorf("[%s] --selector is not restored (got: %s)", test.description, o.labelSelector)
+				return
+			}
+			if actual == nil || actual.Error() != test.expected {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetImageSet(t *testing.T) {
+
+	expected := []v1.ContainerImage{
+		{Names: []string{"image-common"}, SizeBytes: 1200},
+		{Names: []string{"image-a"}, SizeBytes: 3000},
+		{Names: []string{"image-big"}, SizeBytes: 5000},
+	}
+
+	actual := getImageSet(testCompareNodes[:2])
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestImageIndex(t *testing.T) {
+
+	images := []v1.ContainerImage{
+		{Names: []string{"nginx@sha256:3e2f4b", "nginx:1.17"}},
+		{Names: []string{"gcr.io/project/app:v1"}},
+		{Names: []string{"docker.io/library/nginx:1.17"}},
+	}
+
+	var tests = []struct {
+		description string
+		image       v1.ContainerImage
+		expected    int
+	}{
+		{"normalized name", v1.ContainerImage{Names: []string{"docker.io/library/nginx@sha256:3e2f4b"}}, 0},
+		{"any name", v1.ContainerImage{Names: []string{"unknown", "gcr.io/project/app:v1"}}, 1},
+		{"first image", v1.ContainerImage{Names: []string{"nginx:1.17"}}, 0},
+		{"not found", v1.ContainerImage{Names: []string{"nginx:1.16"}}, -1},
+	}
+
+	index := newImageIndex(images)
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := index.find(test.image)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/kubelet"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// kubeletColumns defines columns of kubelet configuration
+var kubeletColumns = []table.Column{
+	{Name: "GC HIGH", Type: table.Percent},
+	{Name: "EST. TO GC", Type: table.Bytes},
+	{Name: "EVICTION", Type: table.String},
+	{Name: "EST. TO EVICTION", Type: table.Bytes},
+}
+
+// configzFailures defines kinds of failures to get kubelet configuration in order of warnings
+var configzFailures = []struct {
+	kind string
+	hint string
+}{
+	{"forbidden", "permission for nodes/proxy resource is required"},
+	{"not found", "kubelet may not serve /configz"},
+	{"fetch", "failed to fetch /configz"},
+	{"parse", "failed to parse /configz"},
+}
+
+// configzFailure is nodes failed to get kubelet configuration by a kind of failure
+type configzFailure struct {
+	nodes []string
+	err   error
+}
+
+// getKubeletConfigs fetches kubelet configuration of nodes concurrently with --concurrency workers
+// Config of node is nil if it can not be fetched (e.g. forbidden node proxy).
+// A warning is printed for each kind of failure.
+func (o *DfiOptions) getKubeletConfigs(nodes []v1.Node) map[string]*kubelet.Config {
+
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]*kubelet.Config, len(nodes))
+	kinds := make([]string, len(nodes))
+	errs := make([]error, len(nodes))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(nodes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				b, err := o.configzGetter(nodes[i].ObjectMeta.Name)
+				if err != nil {
+					kinds[i], errs[i] = getConfigzFailureKind(err), err
+					continue
+				}
+				results[i], errs[i] = kubelet.ParseConfigz(b)
+				if errs[i] != nil {
+					kinds[i] = "parse"
+				}
+			}
+		}()
+	}
+	for i := range nodes {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	// failures are collected in order of nodes
+	configs := map[string]*kubelet.Config{}
+	failures := map[string]*configzFailure{}
+	for i, node := range nodes {
+		name := node.ObjectMeta.Name
+		if errs[i] != nil {
+			if failures[kinds[i]] == nil {
+				failures[kinds[i]] = &configzFailure{err: errs[i]}
+			}
+			failures[kinds[i]].nodes = append(failures[kinds[i]].nodes, name)
+			configs[name] = nil
+			continue
+		}
+		configs[name] = results[i]
+	}
+
+	for _, f := range configzFailures {
+		if failure, ok := failures[f.kind]; ok {
+			fmt.Fprintf(
+				o.ErrOut,
+				"warning: kubelet configuration of %d node(s) is not available, %s (e.g. %s: %v)\n",
+				len(failure.nodes),
+				f.hint,
+				failure.nodes[0],
+				failure.err,
+			)
+		}
+	}
+
+	return configs
+}
+
+// getConfigzFailureKind returns kind of error to fetch /configz
+func getConfigzFailureKind(err error) string {
+
+	switch {
+	case apierrors.IsForbidden(err):
+		return "forbidden"
+	case apierrors.IsNotFound(err):
+		return "not found"
+	default:
+		return "fetch"
+	}
+}
+
+// getKubeletThresholds returns warn and crit thresholds of node
+// GC low and high thresholds of kubelet are used with --kubelet-thresholds.
+func (o *DfiOptions) getKubeletThresholds(c *kubelet.Config) (int64, int64) {
+
+	if !o.kubeletThresholds || c == nil {
+		return o.warnThreshold, o.critThreshold
+	}
+
+	return int64(c.ImageGCLowThresholdPercent), int64(c.ImageGCHighThresholdPercent)
+}
+
+// getKubeletColumns returns distance from image GC and eviction
+// Usage of imagefs is estimated by image usage only, so writable layers,
+// logs and other files on the filesystem are not counted and the distance
+// is an upper bound. Distance of 0 bytes is
+// printed as 0 to be distinguished from "N/A" of unknown configuration.
+func (o *DfiOptions) getKubeletColumns(c *kubelet.Config, used, capacity int64) []table.Cell {
+
+	na := table.Cell{Text: "N/A"}
+	if c == nil || capacity == 0 {
+		return []table.Cell{na, na, na, na}
+	}
+
+	// image GC runs when usage exceeds high threshold
+	high := int64(c.ImageGCHighThresholdPercent)
+	gcHigh := thresholdCell(high)
+	toGC := capacity*high/100 - used
+
+	// eviction happens when available is less than threshold
+	eviction, toEviction := na, na
+	if threshold, s, err := c.GetEvictionThreshold(capacity); err == nil && s != "" {
+		eviction = table.Cell{Text: s}
+		toEviction = o.bytesOrZeroCell(capacity - threshold - used)
+	}
+
+	return []table.Cell{gcHigh, o.bytesOrZeroCell(toGC), eviction, toEviction}
+}
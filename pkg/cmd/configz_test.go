@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	color "github.com/gookit/color"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/kubelet"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// testConfigz is response of /configz for node1
+const testConfigz = `{"kubeletconfig":{"imageGCHighThresholdPercent":90,"imageGCLowThresholdPercent":0,"evictionHard":{"imagefs.available":"1Gi"}}}`
+
+// testConfigzGetter returns configz of node1 and error for other nodes
+func testConfigzGetter(node string) ([]byte, error) {
+	if node == "node1" {
+		return []byte(testConfigz), nil
+	}
+	return nil, fmt.Errorf("nodes %q is forbidden", node)
+}
+
+func TestGetKubeletConfigs(t *testing.T) {
+
+	errBuffer := &bytes.Buffer{}
+	o := &DfiOptions{
+		IOStreams:     genericclioptions.IOStreams{ErrOut: errBuffer},
+		configzGetter: testConfigzGetter,
+	}
+
+	expected := map[string]*kubelet.Config{
+		"node1": {
+			ImageGCHighThresholdPercent: 90,
+			ImageGCLowThresholdPercent:  0,
+			EvictionHard:                map[string]string{"imagefs.available": "1Gi"},
+		},
+		"node2": nil,
+	}
+
+	actual := o.getKubeletConfigs(testNodes)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+
+	warning := "warning: kubelet configuration of 1 node(s) is not available, failed to fetch /configz (e.g. node2: nodes \"node2\" is forbidden)\n"
+	if errBuffer.String() != warning {
+		t.Errorf("expected(%s) differ (got: %s)", warning, errBuffer.String())
+	}
+}
+
+func TestGetKubeletConfigsWarnings(t *testing.T) {
+
+	nodes := []v1.Node{}
+	for _, name := range []string{"n1", "n2", "n3", "n4", "n5", "n6"} {
+		nodes = append(nodes, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	// warnings are in order of nodes with concurrent workers
+	errBuffer := &bytes.Buffer{}
+	o := &DfiOptions{
+		IOStreams:   genericclioptions.IOStreams{ErrOut: errBuffer},
+		concurrency: 3,
+		configzGetter: func(node string) ([]byte, error) {
+			resource := schema.GroupResource{Resource: "nodes"}
+			switch node {
+			case "n2", "n3":
+				return nil, apierrors.NewForbidden(resource, node, fmt.Errorf("no permission"))
+			case "n4":
+				return nil, apierrors.NewNotFound(resource, node)
+			case "n5":
+				return []byte("{"), nil
+			case "n6":
+				return nil, fmt.Errorf("connection refused")
+			}
+			return []byte(testConfigz), nil
+		},
+	}
+
+	configs := o.getKubeletConfigs(nodes)
+	if configs["n1"] == nil || len(configs) != len(nodes) {
+		t.Errorf("unexpected configs: %#v", configs)
+	}
+
+	// a warning for each kind of failure
+	expected := strings.Join([]string{
+		`warning: kubelet configuration of 2 node(s) is not available, permission for nodes/proxy resource is required (e.g. n2: nodes "n2" is forbidden: no permission)`,
+		`warning: kubelet configuration of 1 node(s) is not available, kubelet may not serve /configz (e.g. n4: nodes "n4" not found)`,
+		`warning: kubelet configuration of 1 node(s) is not available, failed to fetch /configz (e.g. n6: connection refused)`,
+		`warning: kubelet configuration of 1 node(s) is not available, failed to parse /configz (e.g. n5: failed to parse configz: unexpected end of JSON input)`,
+		"",
+	}, "\n")
+	if errBuffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, errBuffer.String())
+	}
+}
+
+func TestGetKubeletThresholds(t *testing.T) {
+
+	var tests = []struct {
+		description       string
+		kubeletThresholds bool
+		config            *kubelet.Config
+		expectedWarn      int64
+		expectedCrit      int64
+	}{
+		{"disabled", false, kubelet.NewConfig(), 25, 50},
+		{"no config", true, nil, 25, 50},
+		{"kubelet thresholds", true, kubelet.NewConfig(), 80, 85},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{
+				warnThreshold:     25,
+				critThreshold:     50,
+				kubeletThresholds: test.kubeletThresholds,
+			}
+			warn, crit := o.getKubeletThresholds(test.config)
+			if warn != test.expectedWarn || crit != test.expectedCrit {
+				t.Errorf(
+					"[%s] expected(%d, %d) differ (got: %d, %d)",
+					test.description,
+					test.expectedWarn,
+					test.expectedCrit,
+					warn,
+					crit,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetKubeletColumns(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		config      *kubelet.Config
+		used        int64
+		capacity    int64
+		expected    []string
+		expectedRaw []string
+	}{
+		{"default config", kubelet.NewConfig(), 500, 1000, []string{"85%", "350B", "15%", "350B"}, []string{"85", "350", "", "350"}},
+		{"at threshold", kubelet.NewConfig(), 850, 1000, []string{"85%", "0B", "15%", "0B"}, []string{"85", "0", "", "0"}},
+		{"over threshold", kubelet.NewConfig(), 900, 1000, []string{"85%", "-50B", "15%", "-50B"}, []string{"85", "-50", "", "-50"}},
+		{"no eviction", &kubelet.Config{ImageGCHighThresholdPercent: 50}, 100, 1000, []string{"50%", "400B", "N/A", "N/A"}, []string{"50", "400", "", ""}},
+		{"no config", nil, 500, 1000, []string{"N/A", "N/A", "N/A", "N/A"}, []string{"", "", "", ""}},
+		{"no capacity", kubelet.NewConfig(), 500, 0, []string{"N/A", "N/A", "N/A", "N/A"}, []string{"", "", "", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{bytes: true}
+			actual, actualRaw := []string{}, []string{}
+			for _, c := range o.getKubeletColumns(test.config, test.used, test.capacity) {
+				actual = append(actual, c.Text)
+				actualRaw = append(actualRaw, c.Raw)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf(
+					"[%s] expected(%v) differ (got: %v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+			if !reflect.DeepEqual(actualRaw, test.expectedRaw) {
+				t.Errorf(
+					"[%s] expected(%v) differ (got: %v)",
+					test.description,
+					test.expectedRaw,
+					actualRaw,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestDfiKubeletConfig(t *testing.T) {
+
+	t.Run("kubelet config", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			IOStreams:     genericclioptions.IOStreams{ErrOut: &bytes.Buffer{}},
+			nocolor:       true,
+			kubeletConfig: true,
+			table:         table.NewOutputTable(buffer),
+			configzGetter: testConfigzGetter,
+		}
+
+		if err := o.dfi(testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		expected := strings.Join([]string{
+			"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED   GC HIGH   EST. TO GC   EVICTION   EST. TO EVICTION",
+			"node1           1K      5000000K   10000000K      0%       90%     8999999K   1Gi                8926257K",
+			"node2           2K      5000000K   10000000K      0%       N/A          N/A   N/A                     N/A",
+			"",
+		}, "\n")
+
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+
+	t.Run("kubelet thresholds", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			IOStreams:         genericclioptions.IOStreams{ErrOut: &bytes.Buffer{}},
+			warnThreshold:     25,
+			critThreshold:     50,
+			kubeletThresholds: true,
+			table:             table.NewOutputTable(buffer),
+			configzGetter:     testConfigzGetter,
+		}
+
+		if err := o.dfi(testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		// node1 is yellow because GC low threshold is 0
+		yellow := color.FgYellow.Render
+		green := color.FgGreen.Render
+		expected := strings.Join([]string{
+			"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+			"node1           1K      5000000K   10000000K      " + yellow("0%"),
+			"node2           2K      5000000K   10000000K      " + green("0%"),
+			"",
+		}, "\n")
+
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// consistencyLong defines long description
+	consistencyLong = templates.LongDesc(`
+		Find images which exist on only some of nodes in a group.
+
+		Nodes are grouped by value of the label given by --group-by. All nodes
+		are in one group if it is not given. Images missing on some nodes are
+		cache gaps which slow scheduling, or leftovers which waste disk.
+
+		Kubelet reports at most 50 images (--node-status-max-images) by default.
+		Nodes which report 50 images may have images not listed, so they are
+		not counted as missing nodes nor in coverage of such images.
+	`)
+
+	// consistencyExample defines command examples
+	consistencyExample = templates.Examples(`
+		# Find images which are not on all nodes.
+		kubectl dfi consistency
+
+		# Find images which are not on all nodes of each node pool.
+		kubectl dfi consistency --group-by cloud.google.com/gke-nodepool
+
+		# Export coverage of images as csv.
+		kubectl dfi consistency -o csv
+	`)
+)
+
+// ConsistencyOptions is struct of consistency options
+type ConsistencyOptions struct {
+	*DfiOptions
+
+	// consistency options
+	groupBy string
+}
+
+// imageCoverage is nodes which have an image in a group
+type imageCoverage struct {
+	group   string
+	image   v1.ContainerImage
+	nodes   int
+	total   int
+	missing []string
+}
+
+// NewConsistencyOptions is an instance of ConsistencyOptions
+func NewConsistencyOptions(dfi *DfiOptions) *ConsistencyOptions {
+	return &ConsistencyOptions{
+		DfiOptions: dfi,
+		groupBy:    "",
+	}
+}
+
+// NewCmdConsistency is a cobra command wrapping
+func NewCmdConsistency(dfi *DfiOptions) *cobra.Command {
+	o := NewConsistencyOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "consistency [NODE...]",
+		Short:   "Find images which are not on all nodes in a group.",
+		Long:    consistencyLong,
+		Example: consistencyExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// string option
+	cmd.Flags().StringVarP(&o.groupBy, "group-by", "", o.groupBy, `Label key to group nodes.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *ConsistencyOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *ConsistencyOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	return o.validateTableOutput()
+}
+
+// Run printing images which are not on all nodes in groups
+func (o *ConsistencyOptions) Run(args []string) error {
+
+	// get nodes
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: "GROUP", Type: table.String},
+		{Name: "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+		{Name: "NODES", Type: table.String},
+		{Name: "COVERAGE", Type: table.Percent},
+		{Name: "MISSING", Type: table.String},
+	})
+
+	for _, c := range getImageCoverages(nodes, o.groupBy) {
+		name := util.GetImageName(c.image)
+		coverage := countCell(c.nodes * 100 / c.total)
+		coverage.Text += "%"
+		row := []table.Cell{
+			{Text: c.group},
+			o.bytesCell(c.image.SizeBytes),
+			o.imageNameCell(name),
+			{Text: strconv.Itoa(c.nodes) + "/" + strconv.Itoa(c.total)},
+			coverage,
+			{Text: strings.Join(c.missing, ",")},
+		}
+		o.table.AddCells(row)
+	}
+
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+
+	truncated := []string{}
+	for _, node := range nodes {
+		if isImageListTruncated(node) {
+			truncated = append(truncated, node.ObjectMeta.Name)
+		}
+	}
+	o.warnTruncatedNodes(truncated)
+
+	return nil
+}
+
+// getImageCoverages returns images which are not on all nodes in groups
+// Results are sorted by group, then by ratio of coverage in descending order and by image name.
+// Nodes whose images may be truncated by kubelet are counted only if they have the image.
+func getImageCoverages(nodes []v1.Node, groupBy string) []imageCoverage {
+
+	groups := []string{}
+	nodesInGroup := map[string][]v1.Node{}
+	for _, node := range nodes {
+		g := "<all>"
+		if groupBy != "" {
+			g = "<none>"
+			if v, ok := node.ObjectMeta.Labels[groupBy]; ok {
+				g = v
+			}
+		}
+		if _, ok := nodesInGroup[g]; !ok {
+			groups = append(groups, g)
+		}
+		nodesInGroup[g] = append(nodesInGroup[g], node)
+	}
+	sort.Strings(groups)
+
+	coverages := []imageCoverage{}
+	for _, g := range groups {
+		images := getImageSet(nodesInGroup[g])
+
+		// images in the set on each node
+		index := newImageIndex(images)
+		found := make([]map[int]bool, len(nodesInGroup[g]))
+		for n, node := range nodesInGroup[g] {
+			found[n] = map[int]bool{}
+			for _, image := range node.Status.Images {
+				for _, name := range image.Names {
+					if i, ok := index[util.NormalizeImageName(name)]; ok {
+						found[n][i] = true
+					}
+				}
+			}
+		}
+
+		gc := []imageCoverage{}
+		for i, image := range images {
+			c := imageCoverage{group: g, image: image}
+			for n, node := range nodesInGroup[g] {
+				switch {
+				case found[n][i]:
+					c.nodes++
+				case isImageListTruncated(node):
+					continue
+				default:
+					c.missing = append(c.missing, node.ObjectMeta.Name)
+				}
+				c.total++
+			}
+			if len(c.missing) > 0 {
+				gc = append(gc, c)
+			}
+		}
+
+		// totals differ by truncated nodes, so images are sorted by ratio of coverage
+		sort.SliceStable(gc, func(i, j int) bool {
+			ri, rj := gc[i].nodes*gc[j].total, gc[j].nodes*gc[i].total
+			if ri != rj {
+				return ri > rj
+			}
+			return util.GetImageName(gc[i].image) < util.GetImageName(gc[j].image)
+		})
+		coverages = append(coverages, gc...)
+	}
+
+	return coverages
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+func TestNewConsistencyOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &ConsistencyOptions{
+		DfiOptions: dfi,
+		groupBy:    "",
+	}
+
+	actual := NewConsistencyOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestConsistencyRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		groupBy     string
+		output      string
+		expected    []string
+	}{
+		{
+			"all nodes",
+			"",
+			"",
+			[]string{
+				"GROUP   IMAGE SIZE   IMAGE NAME   NODES   COVERAGE   MISSING",
+				"<all>        3000B   image-a      1/3          33%   a2,b1",
+				"<all>        2000B   image-b      1/3          33%   a1,a2",
+				"<all>        5000B   image-big    1/3          33%   a2,b1",
+				"",
+			},
+		},
+		{
+			"group by pool",
+			"pool",
+			"",
+			[]string{
+				"GROUP   IMAGE SIZE   IMAGE NAME   NODES   COVERAGE   MISSING",
+				"a            3000B   image-a      1/2          50%   a2",
+				"a            5000B   image-big    1/2          50%   a2",
+				"",
+			},
+		},
+		{
+			"csv",
+			"pool",
+			"csv",
+			[]string{
+				"GROUP,IMAGE SIZE,IMAGE NAME,NODES,COVERAGE,MISSING",
+				"a,3000,image-a,1/2,50,a2",
+				"a,5000,image-big,1/2,50,a2",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testCompareNodes[0], &testCompareNodes[1], &testCompareNodes[2])
+
+			buffer := &bytes.Buffer{}
+			o := &ConsistencyOptions{
+				DfiOptions: &DfiOptions{
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				groupBy: test.groupBy,
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestGetImageCoverages(t *testing.T) {
+
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"pool": "x"}},
+			Status: v1.NodeStatus{Images: []v1.ContainerImage{
+				{Names: []string{"image1"}, SizeBytes: 100},
+				{Names: []string{"image2"}, SizeBytes: 200},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n2", Labels: map[string]string{"pool": "x"}},
+			Status: v1.NodeStatus{Images: []v1.ContainerImage{
+				{Names: []string{"image1"}, SizeBytes: 100},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n3", Labels: map[string]string{"pool": "x"}},
+			Status: v1.NodeStatus{Images: []v1.ContainerImage{
+				{Names: []string{"image1"}, SizeBytes: 100},
+				{Names: []string{"image2"}, SizeBytes: 200},
+				{Names: []string{"image3"}, SizeBytes: 300},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n4"},
+			Status: v1.NodeStatus{Images: []v1.ContainerImage{
+				{Names: []string{"image1"}, SizeBytes: 100},
+			}},
+		},
+	}
+
+	expected := []imageCoverage{
+		{group: "x", image: nodes[0].Status.Images[1], nodes: 2, total: 3, missing: []string{"n2"}},
+		{group: "x", image: nodes[2].Status.Images[2], nodes: 1, total: 3, missing: []string{"n1", "n2"}},
+	}
+
+	// n4 is the only node in <none> group, so nothing is missing
+	actual := getImageCoverages(nodes, "pool")
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestGetImageCoveragesOrder(t *testing.T) {
+
+	x := testTruncatedNode("t1").Status.Images[0]
+	y := v1.ContainerImage{Names: []string{"y"}, SizeBytes: 100}
+	z := v1.ContainerImage{Names: []string{"z"}, SizeBytes: 100}
+	nodes := []v1.Node{
+		testTruncatedNode("t1"),
+		testTruncatedNode("t2"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "n1"}, Status: v1.NodeStatus{Images: []v1.ContainerImage{x, z, y}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "n2"}, Status: v1.NodeStatus{Images: []v1.ContainerImage{y}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "n3"}, Status: v1.NodeStatus{Images: []v1.ContainerImage{z}}},
+	}
+
+	// x is on more nodes (3/5) but y and z cover more of nodes (2/3)
+	expected := []string{"y", "z", x.Names[0]}
+
+	actual := []string{}
+	for _, c := range getImageCoverages(nodes, "") {
+		if name := c.image.Names[0]; name == "y" || name == "z" || name == x.Names[0] {
+			actual = append(actual, name)
+		}
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+	}
+}
+
+func TestGetImageCoveragesTruncated(t *testing.T) {
+
+	other := v1.ContainerImage{Names: []string{"other"}, SizeBytes: 100}
+	nodes := []v1.Node{
+		testTruncatedNode("t1"),
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Status:     v1.NodeStatus{Images: []v1.ContainerImage{testTruncatedNode("t1").Status.Images[0]}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "n2"},
+			Status:     v1.NodeStatus{Images: []v1.ContainerImage{other}},
+		},
+	}
+
+	// t1 may have the image, so it is not missing
+	expected := imageCoverage{group: "<all>", image: other, nodes: 1, total: 2, missing: []string{"n1"}}
+
+	for _, actual := range getImageCoverages(nodes, "") {
+		if actual.image.Names[0] != "other" {
+			continue
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+		}
+		return
+	}
+	t.Errorf("coverage of other is not found")
+}
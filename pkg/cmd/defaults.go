@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// envPrefix is prefix of environment variables of flags
+	// e.g. KUBECTL_DFI_WARN_THRESHOLD for --warn-threshold
+	envPrefix = "KUBECTL_DFI_"
+
+	// envConfig is environment variable of path to config file
+	envConfig = envPrefix + "CONFIG"
+)
+
+var (
+	// defaultConfigFile is path of config file which has defaults of flags
+	defaultConfigFile = filepath.Join(homedir.HomeDir(), ".kube", "dfi.yaml")
+
+	// ignoredDefaultFlags are flags which can not have defaults
+	ignoredDefaultFlags = []string{"help", "version"}
+)
+
+// applyDefaults sets defaults of flags which are not specified in command line
+// Defaults are taken from environment variables, the profile in config file
+// and top level of config file in this order.
+// Flags of subcommands are set under name of the subcommand because they have
+// different meanings (e.g. --group-by of pulls and consistency).
+func (o *DfiOptions) applyDefaults(c *cobra.Command) error {
+
+	path := o.configFile
+	if p, ok := os.LookupEnv(envConfig); ok {
+		path = p
+	}
+
+	conf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	// profile is needed before other defaults
+	profile := o.profile
+	if !c.Flags().Changed("profile") {
+		if p, ok := os.LookupEnv(getFlagEnv("profile")); ok {
+			profile = p
+		} else if p, ok := conf["profile"]; ok {
+			profile = fmt.Sprint(p)
+		}
+	}
+
+	root := c.Root()
+	commands := map[string]*cobra.Command{}
+	for _, sub := range root.Commands() {
+		commands[sub.Name()] = sub
+	}
+
+	values, err := getConfigValues(conf, profile, commands)
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	// typo in config should not be ignored silently
+	if err := validateConfigValues(values, root); err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	// command is empty for root command
+	command := ""
+	if c != root {
+		command = c.Name()
+	}
+
+	var ret error
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if ret != nil || f.Changed || containsString(ignoredDefaultFlags, f.Name) {
+			return
+		}
+
+		// global flags can be set for all commands and flags of subcommands
+		// only for the subcommand (e.g. KUBECTL_DFI_PULLS_GROUP_BY)
+		global := command == "" || root.PersistentFlags().Lookup(f.Name) != nil
+
+		// value is set directly to keep Changed for flags in command line
+		envs := []string{}
+		if command != "" {
+			envs = append(envs, getFlagEnv(command+"-"+f.Name))
+		}
+		if global {
+			envs = append(envs, getFlagEnv(f.Name))
+		}
+		for _, env := range envs {
+			if v, ok := os.LookupEnv(env); ok {
+				if err := f.Value.Set(v); err != nil {
+					ret = fmt.Errorf("invalid value of %s: %v", env, err)
+				}
+				return
+			}
+		}
+
+		v, ok := values[command][f.Name]
+		if !ok && global {
+			v, ok = values[""][f.Name]
+		}
+		if ok {
+			if err := f.Value.Set(v); err != nil {
+				ret = fmt.Errorf("invalid value of %s in %s: %v", f.Name, path, err)
+			}
+		}
+	})
+
+	return ret
+}
+
+// validateConfigValues returns error if values have unknown flags
+func validateConfigValues(values map[string]map[string]string, root *cobra.Command) error {
+
+	// top level has flags of root command
+	for _, name := range sortedKeys(values[""]) {
+		if getFlagNames(root)[name] {
+			continue
+		}
+		for _, sub := range root.Commands() {
+			if getFlagNames(sub)[name] {
+				return fmt.Errorf("unknown flag: %s (set it under name of the subcommand, e.g. %s.%s)", name, sub.Name(), name)
+			}
+		}
+		return fmt.Errorf("unknown flag: %s", name)
+	}
+
+	for _, sub := range root.Commands() {
+		for _, name := range sortedKeys(values[sub.Name()]) {
+			if !getFlagNames(sub)[name] {
+				return fmt.Errorf("unknown flag of %s: %s", sub.Name(), name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadConfig returns values in config file
+// It returns no values if the file does not exist.
+func loadConfig(path string) (map[string]interface{}, error) {
+
+	conf := map[string]interface{}{}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	if err := yaml.Unmarshal(b, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	if conf == nil {
+		conf = map[string]interface{}{}
+	}
+
+	return conf, nil
+}
+
+// getConfigValues returns flag values in config merged with the profile
+// Values are keyed by name of subcommand and top level values have empty name.
+// Values are converted to strings of command line (e.g. lists are comma separated).
+func getConfigValues(conf map[string]interface{}, profile string, commands map[string]*cobra.Command) (map[string]map[string]string, error) {
+
+	values := map[string]map[string]string{"": {}}
+	if err := addConfigValues(values, conf, "", commands); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		return values, nil
+	}
+
+	profiles, ok := conf["profiles"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile not found: %s", profile)
+	}
+	p, ok := profiles[profile].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile not found: %s", profile)
+	}
+	if err := addConfigValues(values, p, "profiles."+profile+".", commands); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// addConfigValues adds values in a level of config (top level or a profile)
+func addConfigValues(values map[string]map[string]string, conf map[string]interface{}, prefix string, commands map[string]*cobra.Command) error {
+
+	for k, v := range conf {
+		if prefix == "" && k == "profiles" {
+			continue
+		}
+
+		// values of subcommand
+		if m, ok := v.(map[string]interface{}); ok && commands[k] != nil {
+			if values[k] == nil {
+				values[k] = map[string]string{}
+			}
+			for name, cv := range m {
+				s, err := getConfigValue(cv)
+				if err != nil {
+					return fmt.Errorf("%s%s.%s: %v", prefix, k, name, err)
+				}
+				values[k][name] = s
+			}
+			continue
+		}
+
+		s, err := getConfigValue(v)
+		if err != nil {
+			return fmt.Errorf("%s%s: %v", prefix, k, err)
+		}
+		values[""][k] = s
+	}
+
+	return nil
+}
+
+// getConfigValue returns string of a value in config
+func getConfigValue(v interface{}) (string, error) {
+
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case []interface{}:
+		items := []string{}
+		for _, i := range t {
+			s, err := getConfigValue(i)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value: %v", v)
+	}
+}
+
+// getFlagEnv returns name of environment variable of flag
+func getFlagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// getFlagNames returns names of flags of command including inherited flags
+func getFlagNames(c *cobra.Command) map[string]bool {
+
+	names := map[string]bool{}
+	add := func(f *pflag.Flag) {
+		names[f.Name] = true
+	}
+	c.Flags().VisitAll(add)
+	c.PersistentFlags().VisitAll(add)
+	c.InheritedFlags().VisitAll(add)
+
+	return names
+}
+
+// sortedKeys returns keys of map in order
+func sortedKeys(m map[string]string) []string {
+
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
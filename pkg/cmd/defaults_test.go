@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// testConfig is config file of defaults
+var testConfig = `
+gigabytes: true
+binary-prefix: true
+warn-threshold: 60
+crit-threshold: 80
+consistency:
+  group-by: pool
+profiles:
+  prod:
+    selector: env=prod
+    warn-threshold: 70
+    registry: [gcr.io, docker.io]
+    pulls:
+      group-by: registry
+      warn-threshold: 75
+`
+
+// testReadmeConfig is config file in README
+var testReadmeConfig = `
+gigabytes: true
+binary-prefix: true
+warn-threshold: 60
+crit-threshold: 80
+pulls:
+  group-by: registry
+profiles:
+  prod:
+    selector: env=prod
+    registry: [gcr.io, docker.io]
+    consistency:
+      group-by: cloud.google.com/gke-nodepool
+    trend:
+      group-by: cloud.google.com/gke-nodepool
+`
+
+// testApplyDefaults runs defaults of root command for command of args
+func testApplyDefaults(t *testing.T, args []string) (*cobra.Command, error) {
+
+	root := NewCmdDfi(
+		genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr},
+		"v0.0.1",
+		"abcd123",
+		"1234567890",
+	)
+
+	c, flags, err := root.Find(args)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return nil, err
+	}
+	if err := c.ParseFlags(flags); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return nil, err
+	}
+
+	return c, root.PersistentPreRunE(c, []string{})
+}
+
+func TestApplyDefaults(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dfi.yaml")
+	if err := ioutil.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	os.Setenv(envConfig, path)
+	defer os.Unsetenv(envConfig)
+
+	var tests = []struct {
+		description string
+		args        []string
+		env         map[string]string
+		expected    map[string]string
+	}{
+		{
+			"config",
+			[]string{},
+			map[string]string{},
+			map[string]string{"gigabytes": "true", "binary-prefix": "true", "warn-threshold": "60", "crit-threshold": "80", "selector": ""},
+		},
+		{
+			"profile",
+			[]string{"--profile", "prod"},
+			map[string]string{},
+			map[string]string{"warn-threshold": "70", "crit-threshold": "80", "selector": "env=prod", "registry": "[gcr.io,docker.io]"},
+		},
+		{
+			"profile in environment variable",
+			[]string{},
+			map[string]string{"KUBECTL_DFI_PROFILE": "prod"},
+			map[string]string{"warn-threshold": "70", "selector": "env=prod"},
+		},
+		{
+			"environment variable",
+			[]string{"--profile", "prod"},
+			map[string]string{"KUBECTL_DFI_WARN_THRESHOLD": "65", "KUBECTL_DFI_NO_COLOR": "true"},
+			map[string]string{"warn-threshold": "65", "crit-threshold": "80", "no-color": "true"},
+		},
+		{
+			"command line",
+			[]string{"--warn-threshold", "10", "-l", "env=dev"},
+			map[string]string{"KUBECTL_DFI_WARN_THRESHOLD": "65"},
+			map[string]string{"warn-threshold": "10", "selector": "env=dev", "gigabytes": "true"},
+		},
+		{
+			"subcommand",
+			[]string{"consistency"},
+			map[string]string{},
+			map[string]string{"group-by": "pool", "warn-threshold": "60"},
+		},
+		{
+			"subcommand in profile",
+			[]string{"pulls", "--profile", "prod"},
+			map[string]string{},
+			map[string]string{"group-by": "registry", "warn-threshold": "75", "selector": "env=prod"},
+		},
+		{
+			"subcommand without values",
+			[]string{"trend", "--profile", "prod"},
+			map[string]string{},
+			map[string]string{"group-by": "", "warn-threshold": "70"},
+		},
+		{
+			"environment variable of subcommand",
+			[]string{"pulls", "--profile", "prod"},
+			map[string]string{"KUBECTL_DFI_PULLS_GROUP_BY": "image", "KUBECTL_DFI_GROUP_BY": "node"},
+			map[string]string{"group-by": "image"},
+		},
+		{
+			"environment variable of other subcommand",
+			[]string{"consistency"},
+			map[string]string{"KUBECTL_DFI_PULLS_GROUP_BY": "image", "KUBECTL_DFI_GROUP_BY": "node"},
+			map[string]string{"group-by": "pool"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			for k, v := range test.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			c, err := testApplyDefaults(t, test.args)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			for name, expected := range test.expected {
+				actual := c.Flags().Lookup(name).Value.String()
+				if actual != expected {
+					t.Errorf("[%s] expected %s(%s) differ (got: %s)", test.description, name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsReadme(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dfi.yaml")
+	if err := ioutil.WriteFile(path, []byte(testReadmeConfig), 0644); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	os.Setenv(envConfig, path)
+	defer os.Unsetenv(envConfig)
+
+	var tests = []struct {
+		description string
+		args        []string
+		expected    map[string]string
+	}{
+		{"dfi", []string{"--profile", "prod"}, map[string]string{"registry": "[gcr.io,docker.io]", "selector": "env=prod"}},
+		{"pulls", []string{"pulls", "--profile", "prod"}, map[string]string{"group-by": "registry", "selector": "env=prod"}},
+		{"consistency", []string{"consistency", "--profile", "prod"}, map[string]string{"group-by": "cloud.google.com/gke-nodepool"}},
+		{"trend", []string{"trend", "--profile", "prod"}, map[string]string{"group-by": "cloud.google.com/gke-nodepool"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			c, err := testApplyDefaults(t, test.args)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			for name, expected := range test.expected {
+				actual := c.Flags().Lookup(name).Value.String()
+				if actual != expected {
+					t.Errorf("[%s] expected %s(%s) differ (got: %s)", test.description, name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsError(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dfi.yaml")
+	os.Setenv(envConfig, path)
+	defer os.Unsetenv(envConfig)
+
+	var tests = []struct {
+		description string
+		config      string
+		args        []string
+		env         map[string]string
+		expected    string
+	}{
+		{
+			"unknown flag",
+			"warn-treshold: 60",
+			[]string{},
+			map[string]string{},
+			"invalid config " + path + ": unknown flag: warn-treshold",
+		},
+		{
+			"unknown profile",
+			testConfig,
+			[]string{"--profile", "dev"},
+			map[string]string{},
+			"invalid config " + path + ": profile not found: dev",
+		},
+		{
+			"invalid value in config",
+			"warn-threshold: high",
+			[]string{},
+			map[string]string{},
+			"invalid value of warn-threshold in " + path + `: strconv.ParseInt: parsing "high": invalid syntax`,
+		},
+		{
+			"invalid value in environment variable",
+			"",
+			[]string{},
+			map[string]string{"KUBECTL_DFI_BYTES": "yes"},
+			`invalid value of KUBECTL_DFI_BYTES: strconv.ParseBool: parsing "yes": invalid syntax`,
+		},
+		{
+			"flag of subcommand in top level",
+			"group-by: pool",
+			[]string{},
+			map[string]string{},
+			"invalid config " + path + ": unknown flag: group-by (set it under name of the subcommand, e.g. consistency.group-by)",
+		},
+		{
+			"unknown flag of subcommand",
+			"pulls: {window: 1h}",
+			[]string{"pulls"},
+			map[string]string{},
+			"invalid config " + path + ": unknown flag of pulls: window",
+		},
+		{
+			"nested value in subcommand",
+			"profiles: {prod: {pulls: {group-by: {key: node}}}}",
+			[]string{"--profile", "prod"},
+			map[string]string{},
+			"invalid config " + path + ": profiles.prod.pulls.group-by: unsupported value: map[key:node]",
+		},
+		{
+			"nested value",
+			"selector: {env: prod}",
+			[]string{},
+			map[string]string{},
+			"invalid config " + path + ": selector: unsupported value: map[env:prod]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			if err := ioutil.WriteFile(path, []byte(test.config), 0644); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			for k, v := range test.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			_, err := testApplyDefaults(t, test.args)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+
+	// missing config has no values
+	actual, err := loadConfig(filepath.Join(os.TempDir(), "kubectl-dfi-missing.yaml"))
+	if err != nil || !reflect.DeepEqual(actual, map[string]interface{}{}) {
+		t.Errorf("unexpected config: %#v, %v", actual, err)
+	}
+}
+
+func TestGetFlagEnv(t *testing.T) {
+
+	var tests = []struct {
+		name     string
+		expected string
+	}{
+		{"bytes", "KUBECTL_DFI_BYTES"},
+		{"warn-threshold", "KUBECTL_DFI_WARN_THRESHOLD"},
+		{"group-by", "KUBECTL_DFI_GROUP_BY"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := getFlagEnv(test.name); actual != test.expected {
+				t.Errorf("expected(%s) differ (got: %s)", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// describeLong defines long description
+	describeLong = templates.LongDesc(`
+		Show details of image disk usage of a node.
+
+		NODE can be a glob pattern of node names. Every matching node is
+		described in turn, except with -o csv or -o tsv which requires the
+		pattern to match a single node.
+
+		Kubelet reports at most 50 images (--node-status-max-images) by default,
+		so image usage may be underestimated if the node has 50 images.
+
+		With -o csv or -o tsv, only top images are printed. Summary of the node
+		is printed by "kubectl dfi NODE -o csv".
+	`)
+
+	// describeExample defines command examples
+	describeExample = templates.Examples(`
+		# Show details of node1.
+		kubectl dfi describe node1
+
+		# Show details of nodes in pool-a.
+		kubectl dfi describe 'pool-a-*'
+
+		# Show details of node1 with top 20 images.
+		kubectl dfi describe node1 --top 20
+
+		# Export top images of node1 as csv.
+		kubectl dfi describe node1 -o csv
+	`)
+)
+
+// DescribeOptions is struct of describe options
+type DescribeOptions struct {
+	*DfiOptions
+
+	// describe options
+	top int
+}
+
+// registryUsage is total size of images of a registry
+type registryUsage struct {
+	registry string
+	images   int
+	bytes    int64
+}
+
+// NewDescribeOptions is an instance of DescribeOptions
+func NewDescribeOptions(dfi *DfiOptions) *DescribeOptions {
+	return &DescribeOptions{
+		DfiOptions: dfi,
+		top:        10,
+	}
+}
+
+// NewCmdDescribe is a cobra command wrapping
+func NewCmdDescribe(dfi *DfiOptions) *cobra.Command {
+	o := NewDescribeOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "describe NODE",
+		Short:   "Show details of image disk usage of a node.",
+		Long:    describeLong,
+		Example: describeExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// int options
+	cmd.Flags().IntVarP(&o.top, "top", "", o.top, `Number of images to show.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *DescribeOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *DescribeOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if o.top < 0 {
+		return fmt.Errorf("--top must not be negative: %d", o.top)
+	}
+
+	return nil
+}
+
+// Run printing details of nodes
+func (o *DescribeOptions) Run(args []string) error {
+
+	// get nodes
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 && isNodePattern(args[0]) {
+		return fmt.Errorf("no nodes match %s", args[0])
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("node %s is filtered out", args[0])
+	}
+
+	o.setTableFormat()
+
+	// records of several nodes can not be told apart
+	if o.isRecordOutput() && len(nodes) > 1 {
+		return fmt.Errorf("%s matches %d nodes, but -o %s requires a single node", args[0], len(nodes), o.output)
+	}
+
+	for i, node := range nodes {
+		if i > 0 {
+			fmt.Fprintln(o.table.Output)
+		}
+		if err := o.describeNode(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describeNode prints details of a node
+func (o *DescribeOptions) describeNode(node v1.Node) error {
+
+	capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+	allocatable, _ := node.Status.Allocatable.StorageEphemeral().AsInt64()
+	used, count := util.GetImageUsage(node.Status.Images)
+
+	// top images
+	sorted := append([]v1.ContainerImage{}, node.Status.Images...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SizeBytes > sorted[j].SizeBytes
+	})
+	if len(sorted) > o.top {
+		sorted = sorted[:o.top]
+	}
+
+	// only top images are printed as records
+	if o.isRecordOutput() {
+		return o.printTopImages(o.table, sorted, "")
+	}
+
+	// sections are indented in text
+	indent := ""
+	if o.table.Format == "" {
+		indent = "  "
+	}
+
+	images := strconv.Itoa(count)
+	if isImageListTruncated(node) {
+		images += " (may be truncated by --node-status-max-images of kubelet)"
+	}
+
+	// summary
+	s := o.newSectionTable()
+	s.AddHeader([]string{"Name:", node.ObjectMeta.Name})
+	s.AddRow([]string{"Kubelet Version:", node.Status.NodeInfo.KubeletVersion})
+	s.AddRow([]string{"Container Runtime:", node.Status.NodeInfo.ContainerRuntimeVersion})
+	s.AddRow([]string{"Capacity:", o.toUnit(capacity)})
+	s.AddRow([]string{"Allocatable:", o.toUnit(allocatable)})
+	s.AddRow([]string{"Image Used:", o.toUnit(used)})
+	s.AddRow([]string{"%Used:", o.getImageDiskUsage(used, capacity)})
+	s.AddRow([]string{"Images:", images})
+	s.AddRow([]string{"DiskPressure:", getDiskPressure(node)})
+	if err := s.Print(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.table.Output, "\nTop %d Images:\n", len(sorted))
+	if err := o.printTopImages(o.newSectionTable(), sorted, indent); err != nil {
+		return err
+	}
+
+	// registries
+	fmt.Fprintln(o.table.Output, "\nRegistries:")
+	r := o.newSectionTable()
+	r.AddColumns([]table.Column{
+		{Name: indent + "REGISTRY", Type: table.String},
+		{Name: "IMAGES", Type: table.Count},
+		{Name: "TOTAL", Type: table.Bytes},
+	})
+	for _, u := range getRegistryUsages(node.Status.Images) {
+		r.AddCells([]table.Cell{{Text: indent + u.registry}, countCell(u.images), o.bytesCell(u.bytes)})
+	}
+	return r.Print()
+}
+
+// printTopImages prints images with indent of the first column
+func (o *DescribeOptions) printTopImages(t *table.OutputTable, images []v1.ContainerImage, indent string) error {
+
+	t.AddColumns([]table.Column{
+		{Name: indent + "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+	})
+	for _, image := range images {
+		name := util.GetImageName(image)
+		t.AddCells([]table.Cell{o.bytesCell(image.SizeBytes), o.imageNameCell(name)})
+	}
+	return t.Print()
+}
+
+// getDiskPressure returns DiskPressure condition of node
+func getDiskPressure(node v1.Node) string {
+
+	for _, c := range node.Status.Conditions {
+		if c.Type != v1.NodeDiskPressure {
+			continue
+		}
+
+		s := string(c.Status) + " since " + c.LastTransitionTime.UTC().Format(time.RFC3339)
+		if c.Reason != "" {
+			s += " (" + c.Reason + ")"
+		}
+		return s
+	}
+
+	return "Unknown"
+}
+
+// getRegistryUsages returns total size of images per registry
+// Registries are sorted by total size in descending order.
+func getRegistryUsages(images []v1.ContainerImage) []registryUsage {
+
+	usages := []registryUsage{}
+	index := map[string]int{}
+	for _, image := range images {
+		registry := "<none>"
+		if len(image.Names) > 0 {
+			registry = util.GetImageRegistry(util.GetImageName(image))
+		}
+
+		i, ok := index[registry]
+		if !ok {
+			i = len(usages)
+			index[registry] = i
+			usages = append(usages, registryUsage{registry: registry})
+		}
+		usages[i].images++
+		usages[i].bytes += image.SizeBytes
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].bytes > usages[j].bytes
+	})
+
+	return usages
+}
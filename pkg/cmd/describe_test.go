@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for describe
+var testDescribeNode = v1.Node{
+	ObjectMeta: metav1.ObjectMeta{Name: "describe1"},
+	Status: v1.NodeStatus{
+		Images: []v1.ContainerImage{
+			{Names: []string{"docker.io/library/nginx@sha256:3e2f4b", "docker.io/library/nginx:1.17.0"}, SizeBytes: 1000},
+			{Names: []string{"gcr.io/project/app@sha256:aaaa", "gcr.io/project/app:v1.2.3"}, SizeBytes: 2000},
+			{Names: []string{"gcr.io/project/sidecar@sha256:bbbb", "gcr.io/project/sidecar:v1"}, SizeBytes: 500},
+		},
+		Capacity: v1.ResourceList{
+			v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+		},
+		Allocatable: v1.ResourceList{
+			v1.ResourceEphemeralStorage: *resource.NewQuantity(8000, resource.DecimalSI),
+		},
+		Conditions: []v1.NodeCondition{
+			{
+				Type:               v1.NodeDiskPressure,
+				Status:             v1.ConditionFalse,
+				Reason:             "KubeletHasNoDiskPressure",
+				LastTransitionTime: metav1.NewTime(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)),
+			},
+		},
+		NodeInfo: v1.NodeSystemInfo{
+			KubeletVersion:          "v1.14.2",
+			ContainerRuntimeVersion: "docker://18.9.7",
+		},
+	},
+}
+
+func TestNewDescribeOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &DescribeOptions{
+		DfiOptions: dfi,
+		top:        10,
+	}
+
+	actual := NewDescribeOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestDescribeValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		top         int
+		expected    string
+	}{
+		{"valid", 10, ""},
+		{"zero", 0, ""},
+		{"negative", -1, "--top must not be negative: -1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DescribeOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				top:        test.top,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestDescribeRun(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testDescribeNode)
+
+	buffer := &bytes.Buffer{}
+	o := &DescribeOptions{
+		DfiOptions: &DfiOptions{
+			bytes:      true,
+			nocolor:    true,
+			table:      table.NewOutputTable(buffer),
+			nodeClient: fakeClient.CoreV1().Nodes(),
+		},
+		top: 2,
+	}
+
+	if err := o.Run([]string{"describe1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"Name:                describe1",
+		"Kubelet Version:     v1.14.2",
+		"Container Runtime:   docker://18.9.7",
+		"Capacity:            10000B",
+		"Allocatable:         8000B",
+		"Image Used:          3500B",
+		"%Used:               35%",
+		"Images:              3",
+		"DiskPressure:        False since 2019-06-01T00:00:00Z (KubeletHasNoDiskPressure)",
+		"",
+		"Top 2 Images:",
+		"  IMAGE SIZE   IMAGE NAME",
+		"       2000B   gcr.io/project/app:v1.2.3",
+		"       1000B   docker.io/library/nginx:1.17.0",
+		"",
+		"Registries:",
+		"  REGISTRY    IMAGES   TOTAL",
+		"  gcr.io           2   2500B",
+		"  docker.io        1   1000B",
+		"",
+	}, "\n")
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestDescribeRunCSV(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testDescribeNode)
+
+	buffer := &bytes.Buffer{}
+	o := &DescribeOptions{
+		DfiOptions: &DfiOptions{
+			bytes:      true,
+			nocolor:    true,
+			output:     "csv",
+			table:      table.NewOutputTable(buffer),
+			nodeClient: fakeClient.CoreV1().Nodes(),
+		},
+		top: 2,
+	}
+
+	if err := o.Run([]string{"describe1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"IMAGE SIZE,IMAGE NAME",
+		"2000,gcr.io/project/app:v1.2.3",
+		"1000,docker.io/library/nginx:1.17.0",
+		"",
+	}, "\n")
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestDescribeRunPattern(t *testing.T) {
+
+	other := testDescribeNode
+	other.ObjectMeta.Name = "describe2"
+	fakeClient := fake.NewSimpleClientset(&testDescribeNode, &other)
+
+	var tests = []struct {
+		description string
+		pattern     string
+		output      string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			"all matches",
+			"describe*",
+			"",
+			[]string{"Name:                describe1", "Name:                describe2"},
+			"",
+		},
+		{
+			"csv",
+			"describe*",
+			"csv",
+			[]string{},
+			"describe* matches 2 nodes, but -o csv requires a single node",
+		},
+		{
+			"no matches",
+			"other*",
+			"",
+			[]string{},
+			"no nodes match other*",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &DescribeOptions{
+				DfiOptions: &DfiOptions{
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				top: 2,
+			}
+
+			err := o.Run([]string{test.pattern})
+			if (err == nil && test.expectedErr != "") || (err != nil && err.Error() != test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// nodes are described in turn with a blank line between them
+			actual := []string{}
+			for _, line := range strings.Split(buffer.String(), "\n") {
+				if strings.HasPrefix(line, "Name:") {
+					actual = append(actual, line)
+				}
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+			if len(test.expected) > 1 && !strings.Contains(buffer.String(), "  docker.io        1   1000B\n\nName:") {
+				t.Errorf("[%s] nodes are not separated (got: %s)", test.description, buffer.String())
+			}
+		})
+	}
+}
+
+func TestDescribeRunTopOverImages(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testDescribeNode)
+
+	buffer := &bytes.Buffer{}
+	o := &DescribeOptions{
+		DfiOptions: &DfiOptions{
+			bytes:      true,
+			nocolor:    true,
+			table:      table.NewOutputTable(buffer),
+			nodeClient: fakeClient.CoreV1().Nodes(),
+		},
+		top: 10,
+	}
+
+	if err := o.Run([]string{"describe1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// header has number of printed images
+	expected := "\nTop 3 Images:\n"
+	if !strings.Contains(buffer.String(), expected) {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestGetDiskPressure(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		node        v1.Node
+		expected    string
+	}{
+		{"condition", testDescribeNode, "False since 2019-06-01T00:00:00Z (KubeletHasNoDiskPressure)"},
+		{"no condition", v1.Node{}, "Unknown"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getDiskPressure(test.node)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetRegistryUsages(t *testing.T) {
+
+	images := append([]v1.ContainerImage{{Names: []string{}, SizeBytes: 100}}, testDescribeNode.Status.Images...)
+
+	expected := []registryUsage{
+		{registry: "gcr.io", images: 2, bytes: 2500},
+		{registry: "docker.io", images: 1, bytes: 1000},
+		{registry: "<none>", images: 1, bytes: 100},
+	}
+
+	actual := getRegistryUsages(images)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
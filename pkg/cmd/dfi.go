@@ -4,8 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/makocchi-git/kubectl-dfi/pkg/history"
+	"github.com/makocchi-git/kubectl-dfi/pkg/kubelet"
 	"github.com/makocchi-git/kubectl-dfi/pkg/table"
 	"github.com/makocchi-git/kubectl-dfi/pkg/util"
 
@@ -15,12 +20,18 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
 	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
 	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/term"
 
 	// Initialize all known client auth plugins.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
+// barWidth is number of marks in bars
+const barWidth = 20
+
 var (
 	// DfLong defines long description
 	dfiLong = templates.LongDesc(`
@@ -46,7 +57,56 @@ var (
 
 		# List images on nodes.
 		kubectl dfi --list
+
+		# Show disk pressure, taints, evictions and disk events of nodes.
+		kubectl dfi -o wide --events-window 24h
+
+		# Show ready and schedulable linux worker nodes matching with the pattern.
+		kubectl dfi 'pool-a-*' --ready-only --schedulable-only --role worker --os linux
+
+		# Show usage of images in gcr.io larger than 100Mi.
+		kubectl dfi --registry gcr.io --min-size 100Mi
+
+		# List images except infrastructure images.
+		kubectl dfi --list --exclude-image 'k8s.gcr.io/|calico/'
+
+		# Show distance to image GC and eviction with kubelet configuration.
+		kubectl dfi --kubelet-config --kubelet-thresholds
+
+		# Print only names and sizes of images without header.
+		kubectl dfi --list --columns "image name,image size" --no-headers
+
+		# Export image list as csv.
+		kubectl dfi --list -o csv
+
+		# Print markdown table with text status markers for pull requests.
+		kubectl dfi -o markdown --status-markers text
+
+		# Draw usage bars of nodes.
+		kubectl dfi --bars
+
+		# Record usage of nodes to show growth with "kubectl dfi trend".
+		kubectl dfi --record
+
+		# Use defaults of "prod" profile in ~/.kube/dfi.yaml.
+		kubectl dfi --profile prod
 	`)
+
+	// defaultHistoryFile is path of history appended by --record
+	defaultHistoryFile = filepath.Join(homedir.HomeDir(), ".kube", "dfi", "history.jsonl")
+
+	// dfiOutputs defines valid values for --output
+	dfiOutputs = []string{"wide", table.CSV, table.TSV, table.Markdown}
+
+	// tableOutputs defines valid values for --output of subcommands
+	tableOutputs = []string{table.CSV, table.TSV, table.Markdown}
+
+	// statusMarkers defines markers of usage levels in markdown for --status-markers
+	statusMarkers = map[string]map[string]string{
+		"emoji": {"ok": "\U0001F7E2", "warn": "\U0001F7E1", "crit": "\U0001F534"},
+		"text":  {"ok": "[OK]", "warn": "[WARN]", "crit": "[CRIT]"},
+		"none":  {},
+	}
 )
 
 // DfiOptions is struct of df options
@@ -54,11 +114,22 @@ type DfiOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 	genericclioptions.IOStreams
 
+	// config options
+	configFile string
+	profile    string
+
 	// general options
 	labelSelector string
+	fieldSelector string
 	count         bool
 	table         *table.OutputTable
 
+	// node fetching options
+	chunkSize       int64
+	concurrency     int
+	continueOnError bool
+	nodeErrors      []nodeError
+
 	// unit options
 	bytes       bool
 	kByte       bool
@@ -71,11 +142,50 @@ type DfiOptions struct {
 	nocolor       bool
 	warnThreshold int64
 	critThreshold int64
+	markers       string
+
+	// node filter options
+	readyOnly       bool
+	schedulableOnly bool
+	role            string
+	operatingSystem string
+	arch            string
 
 	// list options
 	list bool
 
-	// k8s node client
+	// table options
+	columns   []string
+	noHeaders bool
+	noTrunc   bool
+	bars      bool
+
+	// image filter options
+	imageFilter  string
+	excludeImage string
+	minSize      string
+	maxSize      string
+	registries   []string
+
+	// image filter built from image filter options by Validate
+	filter *imageFilter
+
+	// output options
+	output       string
+	eventsWindow time.Duration
+
+	// history options
+	record      bool
+	recordTime  time.Time
+	historyFile string
+
+	// kubelet config options
+	kubeletConfig     bool
+	kubeletThresholds bool
+	configzGetter     func(node string) ([]byte, error)
+
+	// k8s clients
+	clientset  kubernetes.Interface
 	nodeClient clientv1.NodeInterface
 }
 
@@ -83,6 +193,8 @@ type DfiOptions struct {
 func NewDfiOptions(streams genericclioptions.IOStreams) *DfiOptions {
 	return &DfiOptions{
 		configFlags:   genericclioptions.NewConfigFlags(true),
+		configFile:    defaultConfigFile,
+		profile:       "",
 		bytes:         false,
 		kByte:         false,
 		mByte:         false,
@@ -93,9 +205,18 @@ func NewDfiOptions(streams genericclioptions.IOStreams) *DfiOptions {
 		nocolor:       false,
 		warnThreshold: 25,
 		critThreshold: 50,
+		markers:       "emoji",
 		IOStreams:     streams,
 		labelSelector: "",
+		fieldSelector: "",
+		chunkSize:     500,
+		concurrency:   10,
 		list:          false,
+		columns:       []string{},
+		registries:    []string{},
+		output:        "",
+		eventsWindow:  time.Hour,
+		historyFile:   defaultHistoryFile,
 		table:         table.NewOutputTable(os.Stdout),
 	}
 }
@@ -110,6 +231,7 @@ func NewCmdDfi(streams genericclioptions.IOStreams, version, commit, date string
 		Long:    dfiLong,
 		Example: dfiExample,
 		Version: version,
+		Args:    cobra.ArbitraryArgs,
 		RunE: func(c *cobra.Command, args []string) error {
 			c.SilenceUsage = true
 
@@ -127,27 +249,78 @@ func NewCmdDfi(streams genericclioptions.IOStreams, version, commit, date string
 
 			return nil
 		},
+		// defaults in config file and environment variables are applied to all commands
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			return o.applyDefaults(c)
+		},
+		// nodes failed with --continue-on-error are reported after output of all commands
+		PersistentPostRunE: func(c *cobra.Command, args []string) error {
+			return o.reportNodeErrors()
+		},
 	}
 
 	// bool options
-	cmd.Flags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
-	cmd.Flags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
+	cmd.PersistentFlags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
+	cmd.PersistentFlags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
+	cmd.PersistentFlags().BoolVarP(&o.readyOnly, "ready-only", "", o.readyOnly, `Show only ready nodes.`)
+	cmd.PersistentFlags().BoolVarP(&o.continueOnError, "continue-on-error", "", o.continueOnError, `Show nodes which are fetched successfully even if some nodes fail.`)
+	cmd.PersistentFlags().BoolVarP(&o.schedulableOnly, "schedulable-only", "", o.schedulableOnly, `Show only schedulable (not cordoned) nodes.`)
 	cmd.Flags().BoolVarP(&o.count, "count", "c", o.count, `Print number of images.`)
-	cmd.Flags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
+	cmd.PersistentFlags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
 	cmd.Flags().BoolVarP(&o.list, "list", "", o.list, `Show image list on node.`)
+	cmd.Flags().BoolVarP(&o.noHeaders, "no-headers", "", o.noHeaders, `Do not print headers.`)
+	cmd.Flags().BoolVarP(&o.bars, "bars", "", o.bars, `Draw usage bar next to %USED column (not printed with -o csv or -o tsv).`)
+	cmd.Flags().BoolVarP(&o.record, "record", "", o.record, `Append usage of nodes (all images regardless of image filters) to history for "trend" command.`)
+	cmd.Flags().BoolVarP(&o.noTrunc, "no-trunc", "", o.noTrunc, `Do not truncate image names to fit in the terminal width.`)
+	cmd.Flags().BoolVarP(&o.kubeletConfig, "kubelet-config", "", o.kubeletConfig, `Show estimated distance to image GC and eviction with kubelet configuration (/configz). Disk usage is estimated by sizes of images only.`)
+	cmd.Flags().BoolVarP(&o.kubeletThresholds, "kubelet-thresholds", "", o.kubeletThresholds, `Use image GC low/high thresholds of kubelet as warn/crit threshold.`)
+
+	// int options
+	cmd.PersistentFlags().IntVarP(&o.concurrency, "concurrency", "", o.concurrency, `Number of nodes fetched concurrently by name or for kubelet configuration.`)
 
 	// int64 options
-	cmd.Flags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
-	cmd.Flags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
+	cmd.PersistentFlags().Int64VarP(&o.chunkSize, "chunk-size", "", o.chunkSize, `Return large lists of nodes in chunks rather than all at once. Pass 0 to disable.`)
+	cmd.PersistentFlags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
+	cmd.PersistentFlags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
 
 	// string option
-	cmd.Flags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
-
-	o.configFlags.AddFlags(cmd.Flags())
+	cmd.PersistentFlags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
+	cmd.PersistentFlags().StringVarP(&o.fieldSelector, "field-selector", "", o.fieldSelector, `Selector (field query) to filter on. Supports metadata.name and spec.unschedulable.`)
+	cmd.PersistentFlags().StringVarP(&o.markers, "status-markers", "", o.markers, `Markers of usage levels in markdown output. One of emoji|text|none.`)
+	cmd.PersistentFlags().StringVarP(&o.role, "role", "", o.role, `Show only nodes of the role. One of control-plane|worker.`)
+	cmd.PersistentFlags().StringVarP(&o.operatingSystem, "os", "", o.operatingSystem, `Show only nodes of the operating system. One of linux|windows.`)
+	cmd.PersistentFlags().StringVarP(&o.profile, "profile", "", o.profile, `Name of profile in config file (~/.kube/dfi.yaml) to use as defaults of flags.`)
+	cmd.PersistentFlags().StringVarP(&o.historyFile, "history", "", o.historyFile, `Path to history file of usage recorded by --record.`)
+	cmd.PersistentFlags().StringVarP(&o.arch, "arch", "", o.arch, `Show only nodes of the architecture (e.g. amd64, arm64).`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(dfiOutputs, "|")+`.`)
+	cmd.Flags().StringSliceVarP(&o.columns, "columns", "", o.columns, `Comma separated names of columns to print in order (case insensitive).`)
+	o.addImageFilterFlags(cmd.Flags())
+
+	// duration option
+	cmd.Flags().DurationVarP(&o.eventsWindow, "events-window", "", o.eventsWindow, `Time window to count disk events with "-o wide".`)
+
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+
+	// sub commands
+	cmd.AddCommand(NewCmdPods(o))
+	cmd.AddCommand(NewCmdChargeback(o))
+	cmd.AddCommand(NewCmdWorkloads(o))
+	cmd.AddCommand(NewCmdBootstrap(o))
+	cmd.AddCommand(NewCmdPulls(o))
+	cmd.AddCommand(NewCmdGCSim(o))
+	cmd.AddCommand(NewCmdPrepull(o))
+	cmd.AddCommand(NewCmdCompare(o))
+	cmd.AddCommand(NewCmdConsistency(o))
+	cmd.AddCommand(NewCmdFind(o))
+	cmd.AddCommand(NewCmdDescribe(o))
+	cmd.AddCommand(NewCmdReport(o))
+	cmd.AddCommand(NewCmdHistogram(o))
+	cmd.AddCommand(NewCmdTui(o))
+	cmd.AddCommand(NewCmdTrend(o))
 
 	// add the klog flags
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
@@ -167,69 +340,251 @@ func (o *DfiOptions) Prepare() error {
 		return err
 	}
 
-	client := kubernetes.NewForConfigOrDie(restConfig)
-	o.nodeClient = client.CoreV1().Nodes()
+	o.clientset = kubernetes.NewForConfigOrDie(restConfig)
+
+	// nodes are large, so they are fetched with protobuf
+	protoConfig := rest.CopyConfig(restConfig)
+	protoConfig.ContentType = "application/vnd.kubernetes.protobuf"
+	protoConfig.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	o.nodeClient = kubernetes.NewForConfigOrDie(protoConfig).CoreV1().Nodes()
+	o.configzGetter = func(node string) ([]byte, error) {
+		return kubelet.GetConfigz(o.clientset.CoreV1().RESTClient(), node)
+	}
 
 	return nil
 }
 
+// getNamespace returns namespace for namespaced resources
+func (o *DfiOptions) getNamespace(allNamespaces bool) (string, error) {
+
+	if allNamespaces {
+		return metav1.NamespaceAll, nil
+	}
+
+	ns, _, err := o.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", err
+	}
+
+	return ns, nil
+}
+
 // Validate ensures that all required arguments and flag values are provided
 func (o *DfiOptions) Validate() error {
 
+	if o.output != "" && !containsString(dfiOutputs, o.output) {
+		return fmt.Errorf("invalid output: %s (valid values: %s)", o.output, strings.Join(dfiOutputs, ", "))
+	}
+
+	return o.validateCommon()
+}
+
+// validateCommon validates flags shared by all commands except --output
+func (o *DfiOptions) validateCommon() error {
+
 	if o.warnThreshold > o.critThreshold {
 		return fmt.Errorf(
 			"can not set critical threshold less than warn threshold (warn:%d crit:%d)", o.warnThreshold, o.critThreshold,
 		)
 	}
 
+	if _, ok := statusMarkers[o.markers]; o.markers != "" && !ok {
+		return fmt.Errorf("invalid status markers: %s (valid values: emoji, text, none)", o.markers)
+	}
+
+	if o.chunkSize < 0 {
+		return fmt.Errorf("chunk size must not be negative: %d", o.chunkSize)
+	}
+
+	if o.role != "" && !containsString(nodeRoles, o.role) {
+		return fmt.Errorf("invalid role: %s (valid values: %s)", o.role, strings.Join(nodeRoles, ", "))
+	}
+
+	if o.operatingSystem != "" && !containsString(nodeOSes, o.operatingSystem) {
+		return fmt.Errorf("invalid os: %s (valid values: %s)", o.operatingSystem, strings.Join(nodeOSes, ", "))
+	}
+
+	if err := validateFieldSelector(o.fieldSelector); err != nil {
+		return err
+	}
+
+	filter, err := o.getImageFilter()
+	if err != nil {
+		return err
+	}
+	o.filter = filter
+
+	if o.record && o.list {
+		return fmt.Errorf("--record can not be used with --list")
+	}
+
 	return nil
 }
 
 // Run printing disk usage of images
 func (o *DfiOptions) Run(args []string) error {
 
-	// get nodes
-	nodes := []v1.Node{}
-	if len(args) > 0 {
-		for _, a := range args {
-			n, nerr := o.nodeClient.Get(a, metav1.GetOptions{})
-			if nerr != nil {
-				return fmt.Errorf("failed to get node: %v", nerr)
-			}
-			nodes = append(nodes, *n)
-		}
-	} else {
-		na, naerr := o.nodeClient.List(metav1.ListOptions{LabelSelector: o.labelSelector})
-		if naerr != nil {
-			return fmt.Errorf("failed to get nodes: %v", naerr)
-		}
-		nodes = append(nodes, na.Items...)
+	// table options
+	o.table.NoHeaders = o.noHeaders
+	if !o.noTrunc {
+		o.table.MaxWidth = o.getTerminalWidth()
 	}
+	o.setTableFormat()
+
+	// all nodes of a run are recorded at same time
+	o.recordTime = time.Now()
 
 	// list images and return
 	if o.list {
-		if err := o.listImagesOnNode(nodes); err != nil {
+		return o.visitChunks(args, o.listImagesOnNode)
+	}
+
+	// wide output needs all nodes to get pods and events at once
+	if o.output == "wide" {
+		nodes, err := o.getNodes(args)
+		if err != nil {
 			return err
 		}
-		return nil
+		return o.dfi(nodes)
 	}
 
 	// print df image usage
-	if err := o.dfi(nodes); err != nil {
-		return err
+	return o.visitChunks(args, o.dfi)
+}
+
+// visitChunks prints rows of chunks of nodes by fn
+// Rows of csv, tsv and markdown are streamed for each chunk, but rows of
+// aligned columns are kept until all chunks are visited to know widths.
+func (o *DfiOptions) visitChunks(args []string, fn func([]v1.Node) error) error {
+
+	o.table.Buffer = true
+	err := o.visitNodes(args, fn)
+
+	// rows of chunks visited before an error are printed too
+	if err == nil || len(o.table.Rows) > 0 {
+		if ferr := o.table.Flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+
+	return err
+}
+
+// validateTableOutput ensures that --output of subcommands is csv, tsv or markdown
+func (o *DfiOptions) validateTableOutput() error {
+
+	if o.output != "" && !containsString(tableOutputs, o.output) {
+		return fmt.Errorf("invalid output: %s (valid values: %s)", o.output, strings.Join(tableOutputs, ", "))
 	}
 
 	return nil
 }
 
+// setTableFormat prints table as csv or tsv records or markdown with --output
+func (o *DfiOptions) setTableFormat() {
+
+	if containsString(tableOutputs, o.output) {
+		o.table.Format = o.output
+		o.table.Markers = statusMarkers[o.markers]
+	}
+}
+
+// isRecordOutput returns true if --output is csv or tsv
+// Commands print only their main table as records, so output has one header.
+func (o *DfiOptions) isRecordOutput() bool {
+	return o.output == table.CSV || o.output == table.TSV
+}
+
+// newSectionTable returns a table printed after o.table in the same format
+func (o *DfiOptions) newSectionTable() *table.OutputTable {
+
+	t := table.NewOutputTable(o.table.Output)
+	t.Format = o.table.Format
+	t.Markers = o.table.Markers
+
+	return t
+}
+
+// getTerminalWidth returns width of terminal
+// It returns 0 if output is not a terminal, so nothing is truncated.
+func (o *DfiOptions) getTerminalWidth() int {
+
+	f, ok := o.Out.(*os.File)
+	if !ok || !term.IsTerminal(f) {
+		return 0
+	}
+
+	size := term.GetSize(f.Fd())
+	if size == nil {
+		return 0
+	}
+
+	return int(size.Width)
+}
+
+// getNodes returns nodes specified by args or selectors
+func (o *DfiOptions) getNodes(args []string) ([]v1.Node, error) {
+
+	nodes := []v1.Node{}
+	err := o.visitNodes(args, func(n []v1.Node) error {
+		nodes = append(nodes, n...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
 // dfi prints image disk usage
 func (o *DfiOptions) dfi(nodes []v1.Node) error {
 
 	// set printer header
-	headers := []string{"NAME", "IMAGE USED", "ALLOCATABLE", "CAPACITY", "%USED"}
-	o.table.AddHeader(headers)
+	columns := []table.Column{
+		{Name: "NAME", Type: table.String},
+		{Name: "IMAGE USED", Type: table.Bytes},
+		{Name: "ALLOCATABLE", Type: table.Bytes},
+		{Name: "CAPACITY", Type: table.Bytes},
+		{Name: "%USED", Type: table.Percent},
+	}
+
+	// csv and tsv have image count in its own column because raw values are printed
+	countColumn := o.count && o.isRecordOutput()
+	if countColumn {
+		columns = append(columns[:2], append([]table.Column{{Name: "IMAGES", Type: table.Count}}, columns[2:]...)...)
+	}
+	// bars are art for terminals and not values of records
+	bars := o.bars && !o.isRecordOutput()
+	if bars {
+		columns = append(columns, table.Column{Name: "USAGE", Type: table.String})
+	}
+
+	// disk related status for wide output
+	var infos map[string]*nodeDiskInfo
+	if o.output == "wide" {
+		var err error
+		if infos, err = o.getNodeDiskInfo(nodes); err != nil {
+			return err
+		}
+		columns = append(columns, wideColumns...)
+	}
+
+	// kubelet configuration
+	var configs map[string]*kubelet.Config
+	if o.kubeletConfig || o.kubeletThresholds {
+		configs = o.getKubeletConfigs(nodes)
+	}
+	if o.kubeletConfig {
+		columns = append(columns, kubeletColumns...)
+	}
+	o.table.AddColumns(columns)
+	if err := o.table.SelectColumns(o.columns); err != nil {
+		return err
+	}
 
 	// node loop
+	records := []history.Record{}
 	for _, node := range nodes {
 
 		// node name
@@ -242,31 +597,58 @@ func (o *DfiOptions) dfi(nodes []v1.Node) error {
 		allocatable, _ := node.Status.Allocatable.StorageEphemeral().AsInt64()
 
 		// get used storage by images and count images
-		used, count := util.GetImageUsage(node.Status.Images)
+		used, count := util.GetImageUsage(o.filterImages(node.Status.Images))
 
 		// with image count
-		icount := ""
-		if o.count {
-			icount = fmt.Sprintf("(%d)", count)
+		columnUsed := o.bytesCell(used)
+		if o.count && !countColumn {
+			columnUsed.Text += fmt.Sprintf("(%d)", count)
 		}
 
 		// columns
-		columnsPercent := o.getImageDiskUsage(used, capacity)
-		columnUsed := o.toUnit(used) + icount
-		columnAllocatable := o.toUnit(allocatable)
-		columnCapacity := o.toUnit(capacity)
-
-		row := []string{
-			name,
-			columnUsed,
-			columnAllocatable,
-			columnCapacity,
-			columnsPercent,
+		warn, crit := o.getKubeletThresholds(configs[name])
+		row := []table.Cell{{Text: name}, columnUsed}
+		if countColumn {
+			row = append(row, countCell(count))
+		}
+		row = append(row,
+			o.bytesCell(allocatable),
+			o.bytesCell(capacity),
+			o.percentCell(used, capacity, warn, crit),
+		)
+		if bars {
+			row = append(row, table.Cell{Text: o.getUsageBar(used, capacity, warn, crit)})
+		}
+		if infos != nil {
+			row = append(row, infos[name].columns()...)
+		}
+		if o.kubeletConfig {
+			row = append(row, o.getKubeletColumns(configs[name], used, capacity)...)
+		}
+		o.table.AddCells(row)
+
+		// all images are recorded regardless of image filters to keep history consistent
+		if o.record {
+			recordUsed, recordCount := util.GetImageUsage(node.Status.Images)
+			records = append(records, history.Record{
+				Time:        o.recordTime,
+				Node:        name,
+				Labels:      node.ObjectMeta.Labels,
+				Used:        recordUsed,
+				Images:      recordCount,
+				Allocatable: allocatable,
+				Capacity:    capacity,
+			})
 		}
-		o.table.AddRow(row)
 	}
 
-	o.table.Print()
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+
+	if o.record {
+		return history.Append(o.historyFile, records)
+	}
 
 	return nil
 }
@@ -274,8 +656,14 @@ func (o *DfiOptions) dfi(nodes []v1.Node) error {
 func (o *DfiOptions) listImagesOnNode(nodes []v1.Node) error {
 
 	// set printer header
-	headers := []string{"NAME", "IMAGE SIZE", "IMAGE NAME"}
-	o.table.AddHeader(headers)
+	o.table.AddColumns([]table.Column{
+		{Name: "NAME", Type: table.String},
+		{Name: "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+	})
+	if err := o.table.SelectColumns(o.columns); err != nil {
+		return err
+	}
 
 	// node loop
 	for _, node := range nodes {
@@ -283,30 +671,47 @@ func (o *DfiOptions) listImagesOnNode(nodes []v1.Node) error {
 		// node name
 		name := node.ObjectMeta.Name
 
-		for _, i := range node.Status.Images {
-			imageName := i.Names[0]
-			if len(i.Names) > 1 {
-				imageName = i.Names[1]
-			}
-
-			// color tag
-			if !o.nocolor {
-				util.ColorImageTag(&imageName)
-			}
+		for _, i := range o.filterImages(node.Status.Images) {
+			imageName := util.GetImageName(i)
 
-			row := []string{name, o.toUnit(i.SizeBytes), imageName}
-			o.table.AddRow(row)
+			o.table.AddCells([]table.Cell{
+				{Text: name},
+				o.bytesCell(i.SizeBytes),
+				o.imageNameCell(imageName),
+			})
 		}
 	}
 
-	o.table.Print()
-
-	return nil
+	return o.table.Print()
 }
 
 // toUnit calculate and add unit for int64
 func (o *DfiOptions) toUnit(i int64) string {
 
+	unitbytes, unit := o.getUnit()
+
+	// Old kubernetes do not support capacity attribute.
+	if i == 0 {
+		return "N/A"
+	}
+
+	return strconv.FormatInt(i/unitbytes, 10) + unit
+}
+
+// toUnitOrZero is same as toUnit but prints 0 instead of "N/A"
+func (o *DfiOptions) toUnitOrZero(i int64) string {
+
+	if i == 0 {
+		_, unit := o.getUnit()
+		return "0" + unit
+	}
+
+	return o.toUnit(i)
+}
+
+// getUnit returns bytes and string of unit
+func (o *DfiOptions) getUnit() (int64, string) {
+
 	var unitbytes int64
 	var unitstr string
 
@@ -322,15 +727,128 @@ func (o *DfiOptions) toUnit(i int64) string {
 		unit = unitstr
 	}
 
-	// Old kubernetes do not support capacity attribute.
-	if i == 0 {
+	return unitbytes, unit
+}
+
+// bytesCell returns cell of size with unit
+func (o *DfiOptions) bytesCell(b int64) table.Cell {
+	return table.Cell{Text: o.toUnit(b), Value: b, Raw: strconv.FormatInt(b, 10)}
+}
+
+// bytesOrZeroCell is same as bytesCell but prints 0 instead of "N/A"
+func (o *DfiOptions) bytesOrZeroCell(b int64) table.Cell {
+
+	c := o.bytesCell(b)
+	c.Text = o.toUnitOrZero(b)
+	return c
+}
+
+// imageNameCell returns cell of image name whose tag is colored after truncation
+func (o *DfiOptions) imageNameCell(name string) table.Cell {
+
+	c := table.Cell{Text: name}
+	if !o.nocolor {
+		c.Color = util.RenderImageTag
+	}
+	return c
+}
+
+// thresholdCell returns cell of percentage without color
+func thresholdCell(p int64) table.Cell {
+	return table.Cell{Text: strconv.FormatInt(p, 10) + "%", Value: p, Raw: strconv.FormatInt(p, 10)}
+}
+
+// countCell returns cell of number
+func countCell(n int) table.Cell {
+	return table.Cell{Text: strconv.Itoa(n), Value: int64(n), Raw: strconv.Itoa(n)}
+}
+
+// percentCell returns cell of percentage colored by given thresholds
+// Raw value is empty if capacity is unknown.
+func (o *DfiOptions) percentCell(used, capacity, warn, crit int64) table.Cell {
+
+	c := table.Cell{Text: o.getImageDiskUsageWithThresholds(used, capacity, warn, crit)}
+	if capacity != 0 {
+		c.Value = getPercent(used, capacity)
+		c.Raw = strconv.FormatInt(c.Value, 10)
+		c.Status = getUsageLevel(used, capacity, warn, crit)
+	}
+	return c
+}
+
+// getUsageBar returns bar of usage colored by given thresholds
+func (o *DfiOptions) getUsageBar(used, capacity, warn, crit int64) string {
+
+	if capacity == 0 {
 		return "N/A"
 	}
 
-	return strconv.FormatInt(i/unitbytes, 10) + unit
+	bar := drawBar(used, capacity, barWidth)
+
+	if !o.nocolor {
+		util.SetPercentageColor(&bar, getPercent(used, capacity), warn, crit)
+	}
+
+	return bar
+}
+
+// drawBar returns bar proportional to value in max
+// Non-zero value has at least one mark to be distinguished from zero.
+func drawBar(value, max int64, width int) string {
+
+	n := 0
+	if max > 0 {
+		n = int(value * int64(width) / max)
+	}
+	if n == 0 && value > 0 {
+		n = 1
+	}
+	if n > width {
+		n = width
+	}
+
+	return "[" + strings.Repeat("#", n) + strings.Repeat(".", width-n) + "]"
+}
+
+// getUsageLevel returns level of usage by thresholds
+// Levels are same as colors of util.SetPercentageColor.
+func getUsageLevel(used, capacity, warn, crit int64) string {
+
+	if capacity == 0 {
+		return "unknown"
+	}
+
+	p := getPercent(used, capacity)
+	if p < warn {
+		return "ok"
+	}
+	if p < crit {
+		return "warn"
+	}
+	return "crit"
+}
+
+// getPercent returns percentage of used in capacity
+// It returns 0 if capacity is unknown.
+func getPercent(used, capacity int64) int64 {
+
+	if capacity == 0 {
+		return 0
+	}
+
+	p := (used * 100) / capacity
+	if p > 100 {
+		p = 100
+	}
+	return p
 }
 
 func (o *DfiOptions) getImageDiskUsage(used, capacity int64) string {
+	return o.getImageDiskUsageWithThresholds(used, capacity, o.warnThreshold, o.critThreshold)
+}
+
+// getImageDiskUsageWithThresholds returns percentage colored by given thresholds
+func (o *DfiOptions) getImageDiskUsageWithThresholds(used, capacity, warn, crit int64) string {
 
 	var ret string
 
@@ -339,18 +857,13 @@ func (o *DfiOptions) getImageDiskUsage(used, capacity int64) string {
 	if capacity == 0 {
 		ret = "N/A"
 	} else {
-		p := (used * 100) / capacity
-
-		// maybe something wrong
-		if p > 100 {
-			p = 100
-		}
+		p := getPercent(used, capacity)
 
 		ret = strconv.FormatInt(p, 10) + "%"
 
 		// set color
 		if !o.nocolor {
-			util.SetPercentageColor(&ret, p, o.warnThreshold, o.critThreshold)
+			util.SetPercentageColor(&ret, p, warn, crit)
 		}
 	}
 
@@ -3,10 +3,13 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	color "github.com/gookit/color"
 	"github.com/spf13/cobra"
@@ -16,6 +19,7 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	fake "k8s.io/client-go/kubernetes/fake"
 
+	"github.com/makocchi-git/kubectl-dfi/pkg/history"
 	"github.com/makocchi-git/kubectl-dfi/pkg/table"
 )
 
@@ -66,6 +70,7 @@ func TestNewDfiOptions(t *testing.T) {
 
 	expected := &DfiOptions{
 		configFlags:   genericclioptions.NewConfigFlags(true),
+		configFile:    defaultConfigFile,
 		bytes:         false,
 		kByte:         false,
 		mByte:         false,
@@ -76,9 +81,17 @@ func TestNewDfiOptions(t *testing.T) {
 		nocolor:       false,
 		warnThreshold: 25,
 		critThreshold: 50,
+		markers:       "emoji",
 		IOStreams:     streams,
 		labelSelector: "",
+		chunkSize:     500,
+		concurrency:   10,
 		list:          false,
+		columns:       []string{},
+		registries:    []string{},
+		output:        "",
+		eventsWindow:  time.Hour,
+		historyFile:   defaultHistoryFile,
 		table:         table.NewOutputTable(os.Stdout),
 	}
 
@@ -190,11 +203,16 @@ func TestValidate(t *testing.T) {
 		description string
 		warn        int64
 		crit        int64
+		output      string
 		expected    string
 	}{
-		{"crit < warn", 25, 10, "can not set critical threshold less than warn threshold (warn:25 crit:10)"},
-		{"crit > warn", 25, 30, ""},
-		{"warn = crit", 25, 25, ""},
+		{"crit < warn", 25, 10, "", "can not set critical threshold less than warn threshold (warn:25 crit:10)"},
+		{"crit > warn", 25, 30, "", ""},
+		{"warn = crit", 25, 25, "", ""},
+		{"wide output", 25, 30, "wide", ""},
+		{"csv output", 25, 30, "csv", ""},
+		{"markdown output", 25, 30, "markdown", ""},
+		{"invalid output", 25, 30, "yaml", "invalid output: yaml (valid values: wide, csv, tsv, markdown)"},
 	}
 
 	for _, test := range tests {
@@ -202,6 +220,7 @@ func TestValidate(t *testing.T) {
 			o := &DfiOptions{
 				warnThreshold: test.warn,
 				critThreshold: test.crit,
+				output:        test.output,
 			}
 			actual := o.Validate()
 			if actual != nil && actual.Error() != test.expected {
@@ -218,6 +237,55 @@ func TestValidate(t *testing.T) {
 
 }
 
+func TestValidateStatusMarkers(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		markers     string
+		expected    string
+	}{
+		{"emoji", "emoji", ""},
+		{"none", "none", ""},
+		{"invalid", "color", "invalid status markers: color (valid values: emoji, text, none)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{warnThreshold: 25, critThreshold: 50, markers: test.markers}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf("[%s] expected(%#v) differ (got: %#v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestValidateFieldSelector(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		selector    string
+		expected    string
+	}{
+		{"empty", "", ""},
+		{"supported", "metadata.name!=node1,spec.unschedulable=false", ""},
+		{"unsupported", "status.phase=Running", "unsupported field selector: status.phase (valid fields: metadata.name, spec.unschedulable)"},
+		{"invalid", "metadata.name", "invalid field selector: invalid selector: 'metadata.name'; can't understand 'metadata.name'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{warnThreshold: 25, critThreshold: 50, fieldSelector: test.selector}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf("[%s] expected(%#v) differ (got: %#v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
 func TestRun(t *testing.T) {
 
 	var tests = []struct {
@@ -234,9 +302,9 @@ func TestRun(t *testing.T) {
 			false,
 			"",
 			[]string{
-				"NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED",
-				"node1   1K           5000000K      10000000K   0%",
-				"node2   2K           5000000K      10000000K   0%",
+				"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+				"node1           1K      5000000K   10000000K      0%",
+				"node2           2K      5000000K   10000000K      0%",
 				"",
 			},
 			nil,
@@ -247,8 +315,8 @@ func TestRun(t *testing.T) {
 			false,
 			"hostname=node1",
 			[]string{
-				"NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED",
-				"node1   1K           5000000K      10000000K   0%",
+				"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+				"node1           1K      5000000K   10000000K      0%",
 				"",
 			},
 			nil,
@@ -270,8 +338,8 @@ func TestRun(t *testing.T) {
 			false,
 			"",
 			[]string{
-				"NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED",
-				"node2   2K           5000000K      10000000K   0%",
+				"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+				"node2           2K      5000000K   10000000K      0%",
 				"",
 			},
 			nil,
@@ -284,6 +352,32 @@ func TestRun(t *testing.T) {
 			[]string{},
 			fmt.Errorf("failed to get node: nodes \"node3\" not found"),
 		},
+		{
+			"dfi args1 : node pattern",
+			[]string{"node*"},
+			false,
+			"",
+			[]string{
+				"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+				"node1           1K      5000000K   10000000K      0%",
+				"node2           2K      5000000K   10000000K      0%",
+				"",
+			},
+			nil,
+		},
+		{
+			"dfi args2 : node and pattern",
+			[]string{"node2", "node?"},
+			false,
+			"",
+			[]string{
+				"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+				"node2           2K      5000000K   10000000K      0%",
+				"node1           1K      5000000K   10000000K      0%",
+				"",
+			},
+			nil,
+		},
 		{
 			"list args0",
 			[]string{},
@@ -291,8 +385,8 @@ func TestRun(t *testing.T) {
 			"",
 			[]string{
 				"NAME    IMAGE SIZE   IMAGE NAME",
-				"node1   1K           image2",
-				"node2   2K           image1",
+				"node1           1K   image2",
+				"node2           2K   image1",
 				"",
 			},
 			nil,
@@ -341,14 +435,14 @@ func TestDfi(t *testing.T) {
 		}
 
 		// ---
-		// NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED
-		// node1   1K           5000000K      10000000K   0%
-		// node2   2K           5000000K      10000000K   0%
+		// NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED
+		// node1           1K      5000000K   10000000K      0%
+		// node2           2K      5000000K   10000000K      0%
 		// ---
 		lines := []string{
-			"NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED",
-			"node1   1K           5000000K      10000000K   0%",
-			"node2   2K           5000000K      10000000K   0%",
+			"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+			"node1           1K      5000000K   10000000K      0%",
+			"node2           2K      5000000K   10000000K      0%",
 			"",
 		}
 		expected := strings.Join(lines, "\n")
@@ -372,14 +466,42 @@ func TestDfi(t *testing.T) {
 		}
 
 		// ---
-		// NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED
-		// node1   1K(1)        5000000K      10000000K   0%
-		// node2   2K(1)        5000000K      10000000K   0%
+		// NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED
+		// node1        1K(1)      5000000K   10000000K      0%
+		// node2        2K(1)      5000000K   10000000K      0%
 		// ---
 		lines := []string{
-			"NAME    IMAGE USED   ALLOCATABLE   CAPACITY    %USED",
-			"node1   1K(1)        5000000K      10000000K   0%",
-			"node2   2K(1)        5000000K      10000000K   0%",
+			"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+			"node1        1K(1)      5000000K   10000000K      0%",
+			"node2        2K(1)      5000000K   10000000K      0%",
+			"",
+		}
+		expected := strings.Join(lines, "\n")
+		if err := o.dfi(testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+
+	t.Run("with image count in csv", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			nocolor: true,
+			output:  "csv",
+			table:   table.NewOutputTable(buffer),
+			count:   true,
+		}
+		o.setTableFormat()
+
+		lines := []string{
+			"NAME,IMAGE USED,IMAGES,ALLOCATABLE,CAPACITY,%USED",
+			"node1,1000,1,5000000000,10000000000,0",
+			"node2,2000,1,5000000000,10000000000,0",
 			"",
 		}
 		expected := strings.Join(lines, "\n")
@@ -444,10 +566,10 @@ func TestListImagesOnNode(t *testing.T) {
 
 	// ---
 	// NAME    IMAGE SIZE   IMAGE NAME
-	// node1   1K           image2
-	// node2   2K           image1
+	// node1           1K   image2
+	// node2           2K   image1
 	// ---
-	expected := "NAME    IMAGE SIZE   IMAGE NAME\nnode1   1K           image2\nnode2   2K           image1\n"
+	expected := "NAME    IMAGE SIZE   IMAGE NAME\nnode1           1K   image2\nnode2           2K   image1\n"
 
 	if err := o.listImagesOnNode(testNodes); err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -460,6 +582,284 @@ func TestListImagesOnNode(t *testing.T) {
 
 }
 
+func TestRunWithTableOptions(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		list        bool
+		columns     []string
+		noHeaders   bool
+		output      string
+		expected    []string
+		expectedErr error
+	}{
+		{
+			"columns",
+			false,
+			[]string{"%used", "NAME"},
+			false,
+			"",
+			[]string{
+				"%USED   NAME",
+				"   0%   node1",
+				"   0%   node2",
+				"",
+			},
+			nil,
+		},
+		{
+			"no headers",
+			true,
+			[]string{"IMAGE NAME", "IMAGE SIZE"},
+			true,
+			"",
+			[]string{
+				"image2   1K",
+				"image1   2K",
+				"",
+			},
+			nil,
+		},
+		{
+			"invalid column",
+			true,
+			[]string{"AGE"},
+			false,
+			"",
+			[]string{""},
+			fmt.Errorf("invalid column: AGE (valid columns: NAME, IMAGE SIZE, IMAGE NAME)"),
+		},
+		{
+			"csv",
+			false,
+			[]string{},
+			false,
+			"csv",
+			[]string{
+				"NAME,IMAGE USED,ALLOCATABLE,CAPACITY,%USED",
+				"node1,1000,5000000000,10000000000,0",
+				"node2,2000,5000000000,10000000000,0",
+				"",
+			},
+			nil,
+		},
+		{
+			"markdown",
+			false,
+			[]string{"NAME", "%USED"},
+			false,
+			"markdown",
+			[]string{
+				"| NAME | %USED |",
+				"| --- | ---: |",
+				"| node1 | \U0001F7E2 0% |",
+				"| node2 | \U0001F7E2 0% |",
+				"",
+			},
+			nil,
+		},
+		{
+			"tsv list",
+			true,
+			[]string{},
+			false,
+			"tsv",
+			[]string{
+				"NAME\tIMAGE SIZE\tIMAGE NAME",
+				"node1\t1000\timage2",
+				"node2\t2000\timage1",
+				"",
+			},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+
+			buffer := &bytes.Buffer{}
+			o := &DfiOptions{
+				nocolor:       true,
+				list:          test.list,
+				columns:       test.columns,
+				noHeaders:     test.noHeaders,
+				output:        test.output,
+				markers:       "emoji",
+				warnThreshold: 25,
+				critThreshold: 50,
+				table:         table.NewOutputTable(buffer),
+				nodeClient:    fakeClient.CoreV1().Nodes(),
+			}
+
+			err := o.Run([]string{})
+			if !reflect.DeepEqual(err, test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestGetUsageBar(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		used        int64
+		capacity    int64
+		expected    string
+	}{
+		{"empty", 0, 100, "[....................]"},
+		{"small usage", 1, 1000, "[#...................]"},
+		{"half", 50, 100, "[##########..........]"},
+		{"over capacity", 200, 100, "[####################]"},
+		{"unknown capacity", 10, 0, "N/A"},
+	}
+
+	o := &DfiOptions{nocolor: true}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := o.getUsageBar(test.used, test.capacity, 25, 50)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunWithBars(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		columns     []string
+		expected    []string
+	}{
+		{
+			"bars",
+			"",
+			[]string{"NAME", "%USED", "USAGE"},
+			[]string{
+				"NAME    %USED   USAGE",
+				"node1      0%   [#...................]",
+				"node2      0%   [#...................]",
+				"",
+			},
+		},
+		{
+			"csv without bars",
+			"csv",
+			[]string{},
+			[]string{
+				"NAME,IMAGE USED,ALLOCATABLE,CAPACITY,%USED",
+				"node1,1000,5000000000,10000000000,0",
+				"node2,2000,5000000000,10000000000,0",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+
+			buffer := &bytes.Buffer{}
+			o := &DfiOptions{
+				nocolor:       true,
+				bars:          true,
+				output:        test.output,
+				columns:       test.columns,
+				warnThreshold: 25,
+				critThreshold: 50,
+				table:         table.NewOutputTable(buffer),
+				nodeClient:    fakeClient.CoreV1().Nodes(),
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestRunWithRecord(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+
+	o := &DfiOptions{
+		nocolor:       true,
+		record:        true,
+		minSize:       "1500",
+		historyFile:   filepath.Join(dir, "history.jsonl"),
+		warnThreshold: 25,
+		critThreshold: 50,
+		table:         table.NewOutputTable(&bytes.Buffer{}),
+		nodeClient:    fakeClient.CoreV1().Nodes(),
+	}
+
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if err := o.Run([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	records, err := history.Load(o.historyFile)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// nodes of a run have same time
+	if len(records) != 2 || records[0].Time.IsZero() || !records[0].Time.Equal(records[1].Time) {
+		t.Errorf("unexpected records: %#v", records)
+		return
+	}
+
+	// images filtered out of the table are recorded
+	expected := []history.Record{
+		{Node: "node1", Labels: map[string]string{"hostname": "node1"}, Used: 1000, Images: 1, Allocatable: 5000000000, Capacity: 10000000000},
+		{Node: "node2", Used: 2000, Images: 1, Allocatable: 5000000000, Capacity: 10000000000},
+	}
+	for i := range records {
+		records[i].Time = time.Time{}
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, records)
+	}
+
+	// image list is not recorded
+	o.list = true
+	if err := o.Validate(); err == nil || err.Error() != "--record can not be used with --list" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestGetImageDiskUsage(t *testing.T) {
 
 	red := color.FgRed.Render
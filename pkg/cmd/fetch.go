@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// visitNodes calls fn with nodes specified by args or selectors
+// Args can be glob patterns of node names. Nodes are listed in chunks of
+// --chunk-size and fn is called for each chunk, so output can be streamed.
+// Nodes are filtered by node filters and selectors, which are also applied
+// to nodes fetched by name.
+func (o *DfiOptions) visitNodes(args []string, fn func([]v1.Node) error) error {
+
+	labelSelector, err := labels.Parse(o.labelSelector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	fieldSelector, err := fields.ParseSelector(o.fieldSelector)
+	if err != nil {
+		return fmt.Errorf("invalid field selector: %v", err)
+	}
+	match := func(n v1.Node) bool {
+		return labelSelector.Matches(labels.Set(n.ObjectMeta.Labels)) && fieldSelector.Matches(getNodeFields(n))
+	}
+
+	names := []string{}
+	patterns := []string{}
+	for _, a := range args {
+		if isNodePattern(a) {
+			patterns = append(patterns, a)
+		} else {
+			names = append(names, a)
+		}
+	}
+
+	if len(names) > 0 {
+		nodes, err := o.getNodesByName(names)
+		if err != nil {
+			return err
+		}
+		selected := []v1.Node{}
+		for _, n := range nodes {
+			if match(n) {
+				selected = append(selected, n)
+			}
+		}
+		if err := fn(o.filterNodes(selected, nil, nil)); err != nil {
+			return err
+		}
+	}
+
+	// all nodes are fetched by name
+	if len(patterns) == 0 && len(names) > 0 {
+		return nil
+	}
+
+	opts := metav1.ListOptions{LabelSelector: o.labelSelector, FieldSelector: o.fieldSelector, Limit: o.chunkSize}
+	for {
+		na, naerr := o.nodeClient.List(opts)
+		if naerr != nil {
+			return fmt.Errorf("failed to get nodes: %v", naerr)
+		}
+
+		if err := fn(o.filterNodes(na.Items, names, patterns)); err != nil {
+			return err
+		}
+
+		if na.Continue == "" {
+			return nil
+		}
+		opts.Continue = na.Continue
+	}
+}
+
+// getNodesWithoutSelector returns nodes filtered by node filters except --selector
+// It is used by subcommands which select other resources (e.g. pods) by --selector.
+func (o *DfiOptions) getNodesWithoutSelector() ([]v1.Node, error) {
+	return o.getNodesWithSelector("")
+}
+
+// getNodesWithSelector returns nodes filtered by node filters with selector instead of --selector
+func (o *DfiOptions) getNodesWithSelector(selector string) ([]v1.Node, error) {
+
+	saved := o.labelSelector
+	o.labelSelector = selector
+	defer func() { o.labelSelector = saved }()
+
+	return o.getNodes([]string{})
+}
+
+// getNodeFields returns fields of node supported by field selectors of nodes
+func getNodeFields(node v1.Node) fields.Set {
+	return fields.Set{
+		"metadata.name":      node.ObjectMeta.Name,
+		"spec.unschedulable": strconv.FormatBool(node.Spec.Unschedulable),
+	}
+}
+
+// validateFieldSelector ensures that fields of selector are in getNodeFields
+// Nodes fetched by name are matched with the selector by client.
+func validateFieldSelector(selector string) error {
+
+	s, err := fields.ParseSelector(selector)
+	if err != nil {
+		return fmt.Errorf("invalid field selector: %v", err)
+	}
+
+	supported := getNodeFields(v1.Node{})
+	for _, r := range s.Requirements() {
+		if _, ok := supported[r.Field]; !ok {
+			keys := []string{}
+			for k := range supported {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("unsupported field selector: %s (valid fields: %s)", r.Field, strings.Join(keys, ", "))
+		}
+	}
+
+	return nil
+}
+
+// filterNodes returns nodes which match with patterns and node filters
+// Nodes in names are skipped because they are already fetched by name.
+func (o *DfiOptions) filterNodes(nodes []v1.Node, names, patterns []string) []v1.Node {
+
+	filtered := []v1.Node{}
+	for _, n := range nodes {
+		if len(patterns) > 0 && (containsString(names, n.ObjectMeta.Name) || !matchNodePatterns(n.ObjectMeta.Name, patterns)) {
+			continue
+		}
+		if o.matchNode(n) {
+			filtered = append(filtered, n)
+		}
+	}
+
+	return filtered
+}
+
+// nodeError is error of getting a node
+type nodeError struct {
+	name string
+	err  error
+}
+
+// getNodesByName gets nodes concurrently with --concurrency workers
+// Nodes are returned in order of names. With --continue-on-error, failed
+// nodes are skipped and recorded to be reported by reportNodeErrors.
+func (o *DfiOptions) getNodesByName(names []string) ([]v1.Node, error) {
+
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]*v1.Node, len(names))
+	errs := make([]error, len(names))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = o.nodeClient.Get(names[i], metav1.GetOptions{})
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	nodes := []v1.Node{}
+	for i, name := range names {
+		if errs[i] != nil {
+			if !o.continueOnError {
+				return nil, fmt.Errorf("failed to get node: %v", errs[i])
+			}
+			o.nodeErrors = append(o.nodeErrors, nodeError{name: name, err: errs[i]})
+			continue
+		}
+		nodes = append(nodes, *results[i])
+	}
+
+	return nodes, nil
+}
+
+// reportNodeErrors prints nodes which could not be fetched
+// It returns error to exit with non-zero status if any node failed.
+func (o *DfiOptions) reportNodeErrors() error {
+
+	if len(o.nodeErrors) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(o.ErrOut)
+	t := table.NewOutputTable(o.ErrOut)
+	t.AddHeader([]string{"FAILED NODE", "ERROR"})
+	for _, e := range o.nodeErrors {
+		t.AddRow([]string{e.name, e.err.Error()})
+	}
+	if err := t.Print(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("failed to get %d node(s)", len(o.nodeErrors))
+}
+
+// defaultNodeStatusMaxImages is default of --node-status-max-images of kubelet
+const defaultNodeStatusMaxImages = 50
+
+// isImageListTruncated returns true if kubelet may not report all images of the node
+func isImageListTruncated(node v1.Node) bool {
+	return len(node.Status.Images) >= defaultNodeStatusMaxImages
+}
+
+// warnTruncatedNodes prints nodes whose images may not be reported all
+func (o *DfiOptions) warnTruncatedNodes(names []string) {
+
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintf(
+		o.ErrOut,
+		"warning: %d node(s) report %d or more images and may have images not listed (--node-status-max-images of kubelet): %s\n",
+		len(names),
+		defaultNodeStatusMaxImages,
+		strings.Join(names, ", "),
+	)
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// testTruncatedNode returns a node which reports max number of images of 100 bytes
+func testTruncatedNode(name string) v1.Node {
+
+	images := []v1.ContainerImage{}
+	for i := 0; i < defaultNodeStatusMaxImages; i++ {
+		images = append(images, v1.ContainerImage{
+			Names:     []string{fmt.Sprintf("registry.local/image%d:v1", i)},
+			SizeBytes: 100,
+		})
+	}
+
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{
+			Images: images,
+			Capacity: v1.ResourceList{
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+			},
+		},
+	}
+}
+
+// pagingNodeClient lists nodes in chunks
+// Continue token is index of the next node.
+type pagingNodeClient struct {
+	clientv1.NodeInterface
+	nodes []v1.Node
+	calls int
+}
+
+func (c *pagingNodeClient) List(opts metav1.ListOptions) (*v1.NodeList, error) {
+
+	c.calls++
+
+	start, _ := strconv.Atoi(opts.Continue)
+	end := len(c.nodes)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+
+	list := &v1.NodeList{Items: c.nodes[start:end]}
+	if end < len(c.nodes) {
+		list.Continue = strconv.Itoa(end)
+	}
+
+	return list, nil
+}
+
+func TestVisitNodes(t *testing.T) {
+
+	var tests = []struct {
+		description   string
+		args          []string
+		labelSelector string
+		fieldSelector string
+		chunkSize     int64
+		expected      [][]string
+		expectedCalls int
+	}{
+		{"no chunk", []string{}, "", "", 0, [][]string{{"master1", "linux1", "windows1"}}, 1},
+		{"chunk size 2", []string{}, "", "", 2, [][]string{{"master1", "linux1"}, {"windows1"}}, 2},
+		{"chunk size 1", []string{}, "", "", 1, [][]string{{"master1"}, {"linux1"}, {"windows1"}}, 3},
+		{"chunk with pattern", []string{"*1"}, "", "", 2, [][]string{{"master1", "linux1"}, {"windows1"}}, 2},
+		{"names", []string{"linux1", "master1"}, "", "", 1, [][]string{{"linux1", "master1"}}, 0},
+		{"names and pattern", []string{"linux1", "*1"}, "", "", 2, [][]string{{"linux1"}, {"master1"}, {"windows1"}}, 2},
+		{"names with selector", []string{"linux1", "master1"}, "node-role.kubernetes.io/master", "", 1, [][]string{{"master1"}}, 0},
+		{"names with field selector", []string{"linux1", "master1"}, "", "metadata.name!=linux1", 1, [][]string{{"master1"}}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testFilterNodes[0], &testFilterNodes[1], &testFilterNodes[2])
+			nodeClient := &pagingNodeClient{NodeInterface: fakeClient.CoreV1().Nodes(), nodes: testFilterNodes}
+
+			o := &DfiOptions{
+				labelSelector: test.labelSelector,
+				fieldSelector: test.fieldSelector,
+				chunkSize:     test.chunkSize,
+				nodeClient:    nodeClient,
+			}
+
+			actual := [][]string{}
+			err := o.visitNodes(test.args, func(nodes []v1.Node) error {
+				names := []string{}
+				for _, n := range nodes {
+					names = append(names, n.ObjectMeta.Name)
+				}
+				actual = append(actual, names)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+
+			if nodeClient.calls != test.expectedCalls {
+				t.Errorf("[%s] expected(%d) calls differ (got: %d)", test.description, test.expectedCalls, nodeClient.calls)
+				return
+			}
+		})
+	}
+}
+
+func TestRunInChunks(t *testing.T) {
+
+	// the second chunk has a longer name
+	long := testNodes[1]
+	long.ObjectMeta.Name = "node-with-long-name"
+	nodes := []v1.Node{testNodes[0], long}
+
+	fakeClient := fake.NewSimpleClientset(&nodes[0], &nodes[1])
+	nodeClient := &pagingNodeClient{NodeInterface: fakeClient.CoreV1().Nodes(), nodes: nodes}
+
+	buffer := &bytes.Buffer{}
+	o := &DfiOptions{
+		nocolor:    true,
+		chunkSize:  1,
+		table:      table.NewOutputTable(buffer),
+		nodeClient: nodeClient,
+	}
+
+	if err := o.Run([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// rows of all chunks are aligned
+	expected := strings.Join([]string{
+		"NAME                  IMAGE USED   ALLOCATABLE    CAPACITY   %USED",
+		"node1                         1K      5000000K   10000000K      0%",
+		"node-with-long-name           2K      5000000K   10000000K      0%",
+		"",
+	}, "\n")
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+
+	if nodeClient.calls != 2 {
+		t.Errorf("expected(2) calls differ (got: %d)", nodeClient.calls)
+	}
+}
+
+func TestGetNodesByName(t *testing.T) {
+
+	var tests = []struct {
+		description     string
+		names           []string
+		concurrency     int
+		continueOnError bool
+		expected        []string
+		expectedErr     error
+		expectedFailed  []string
+	}{
+		{"sequential", []string{"node2", "node1"}, 1, false, []string{"node2", "node1"}, nil, nil},
+		{"concurrent", []string{"node1", "node2", "node1", "node2"}, 3, false, []string{"node1", "node2", "node1", "node2"}, nil, nil},
+		{"no concurrency", []string{"node1"}, 0, false, []string{"node1"}, nil, nil},
+		{
+			"error",
+			[]string{"node1", "node3"},
+			2,
+			false,
+			nil,
+			fmt.Errorf("failed to get node: nodes \"node3\" not found"),
+			nil,
+		},
+		{
+			"continue on error",
+			[]string{"node4", "node1", "node3", "node2"},
+			2,
+			true,
+			[]string{"node1", "node2"},
+			nil,
+			[]string{"node4", "node3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+
+			o := &DfiOptions{
+				concurrency:     test.concurrency,
+				continueOnError: test.continueOnError,
+				nodeClient:      fakeClient.CoreV1().Nodes(),
+			}
+
+			nodes, err := o.getNodesByName(test.names)
+			if !reflect.DeepEqual(err, test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			actual := []string{}
+			for _, n := range nodes {
+				actual = append(actual, n.ObjectMeta.Name)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+
+			var failed []string
+			for _, e := range o.nodeErrors {
+				failed = append(failed, e.name)
+			}
+			if !reflect.DeepEqual(failed, test.expectedFailed) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expectedFailed, failed)
+				return
+			}
+		})
+	}
+}
+
+func TestReportNodeErrors(t *testing.T) {
+
+	t.Run("no errors", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{IOStreams: genericclioptions.IOStreams{ErrOut: buffer}}
+
+		if err := o.reportNodeErrors(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if buffer.String() != "" {
+			t.Errorf("expected empty output (got: %s)", buffer.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			IOStreams: genericclioptions.IOStreams{ErrOut: buffer},
+			nodeErrors: []nodeError{
+				{name: "node3", err: fmt.Errorf(`nodes "node3" not found`)},
+				{name: "node-typo", err: fmt.Errorf(`nodes "node-typo" not found`)},
+			},
+		}
+
+		err := o.reportNodeErrors()
+		if err == nil || err.Error() != "failed to get 2 node(s)" {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		expected := strings.Join([]string{
+			"",
+			"FAILED NODE   ERROR",
+			`node3         nodes "node3" not found`,
+			`node-typo     nodes "node-typo" not found`,
+			"",
+		}, "\n")
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+}
+
+func TestIsImageListTruncated(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		node        v1.Node
+		expected    bool
+	}{
+		{"some images", testNodes[0], false},
+		{"max images", testTruncatedNode("node1"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if actual := isImageListTruncated(test.node); actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWarnTruncatedNodes(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		names       []string
+		expected    string
+	}{
+		{"no nodes", []string{}, ""},
+		{
+			"nodes",
+			[]string{"node1", "node2"},
+			"warning: 2 node(s) report 50 or more images and may have images not listed (--node-status-max-images of kubelet): node1, node2\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &DfiOptions{IOStreams: genericclioptions.IOStreams{ErrOut: buffer}}
+			o.warnTruncatedNodes(test.names)
+			if buffer.String() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, buffer.String())
+			}
+		})
+	}
+}
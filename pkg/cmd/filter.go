@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/pflag"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// imageFilter filters images on nodes
+type imageFilter struct {
+	include    *regexp.Regexp
+	exclude    *regexp.Regexp
+	minSize    int64
+	maxSize    int64
+	registries []string
+}
+
+// addImageFilterFlags adds flags of image filter
+// Commands which count images add them, and the filter is built by Validate.
+func (o *DfiOptions) addImageFilterFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.imageFilter, "image-filter", "", o.imageFilter, `Count only images whose name matches with the regular expression.`)
+	flags.StringVarP(&o.excludeImage, "exclude-image", "", o.excludeImage, `Do not count images whose name matches with the regular expression.`)
+	flags.StringVarP(&o.minSize, "min-size", "", o.minSize, `Count only images larger than or equal to the size (e.g. 500Mi).`)
+	flags.StringVarP(&o.maxSize, "max-size", "", o.maxSize, `Count only images smaller than or equal to the size (e.g. 1Gi).`)
+	flags.StringSliceVarP(&o.registries, "registry", "", o.registries, `Count only images in the registry (e.g. docker.io, gcr.io).`)
+}
+
+// getImageFilter returns image filter from options
+// It returns nil if no filter is specified.
+func (o *DfiOptions) getImageFilter() (*imageFilter, error) {
+
+	if o.imageFilter == "" && o.excludeImage == "" && o.minSize == "" && o.maxSize == "" && len(o.registries) == 0 {
+		return nil, nil
+	}
+
+	f := &imageFilter{maxSize: -1, registries: o.registries}
+
+	if o.imageFilter != "" {
+		re, err := regexp.Compile(o.imageFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image filter: %v", err)
+		}
+		f.include = re
+	}
+
+	if o.excludeImage != "" {
+		re, err := regexp.Compile(o.excludeImage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude image: %v", err)
+		}
+		f.exclude = re
+	}
+
+	if o.minSize != "" {
+		q, err := resource.ParseQuantity(o.minSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min size: %s", o.minSize)
+		}
+		f.minSize = q.Value()
+	}
+
+	if o.maxSize != "" {
+		q, err := resource.ParseQuantity(o.maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max size: %s", o.maxSize)
+		}
+		f.maxSize = q.Value()
+	}
+
+	if f.maxSize >= 0 && f.minSize > f.maxSize {
+		return nil, fmt.Errorf("can not set max size less than min size (min:%s max:%s)", o.minSize, o.maxSize)
+	}
+
+	return f, nil
+}
+
+// filterImages returns images which match with image filter built by Validate
+func (o *DfiOptions) filterImages(images []v1.ContainerImage) []v1.ContainerImage {
+
+	if o.filter == nil {
+		return images
+	}
+
+	filtered := []v1.ContainerImage{}
+	for _, image := range images {
+		if o.filter.match(image) {
+			filtered = append(filtered, image)
+		}
+	}
+
+	return filtered
+}
+
+// match returns true if image matches with all conditions
+// Name conditions are true if any name of the image matches.
+func (f *imageFilter) match(image v1.ContainerImage) bool {
+
+	if image.SizeBytes < f.minSize {
+		return false
+	}
+
+	if f.maxSize >= 0 && image.SizeBytes > f.maxSize {
+		return false
+	}
+
+	if f.include != nil && !f.matchAnyName(image, f.include.MatchString) {
+		return false
+	}
+
+	if f.exclude != nil && f.matchAnyName(image, f.exclude.MatchString) {
+		return false
+	}
+
+	if len(f.registries) > 0 && !f.matchAnyName(image, func(name string) bool {
+		return containsString(f.registries, util.GetImageRegistry(name))
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// matchAnyName returns true if any name of image satisfies fn
+func (f *imageFilter) matchAnyName(image v1.ContainerImage, fn func(string) bool) bool {
+
+	for _, name := range image.Names {
+		if fn(name) {
+			return true
+		}
+	}
+	return false
+}
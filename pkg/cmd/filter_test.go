@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+func TestGetImageFilter(t *testing.T) {
+
+	var tests = []struct {
+		description  string
+		imageFilter  string
+		excludeImage string
+		minSize      string
+		maxSize      string
+		expected     string
+	}{
+		{"no filter", "", "", "", "", ""},
+		{"valid", "app", "sidecar", "500Mi", "1Gi", ""},
+		{"invalid image filter", "app[", "", "", "", "invalid image filter: error parsing regexp: missing closing ]: `[`"},
+		{"invalid exclude image", "", "(", "", "", "invalid exclude image: error parsing regexp: missing closing ): `(`"},
+		{"invalid min size", "", "", "large", "", "invalid min size: large"},
+		{"invalid max size", "", "", "", "small", "invalid max size: small"},
+		{"min > max", "", "", "1Gi", "500Mi", "can not set max size less than min size (min:1Gi max:500Mi)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{
+				imageFilter:  test.imageFilter,
+				excludeImage: test.excludeImage,
+				minSize:      test.minSize,
+				maxSize:      test.maxSize,
+			}
+			_, actual := o.getImageFilter()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+
+	images := testDescribeNode.Status.Images
+
+	var tests = []struct {
+		description string
+		options     *DfiOptions
+		expected    []v1.ContainerImage
+	}{
+		{"no filter", &DfiOptions{}, images},
+		{"image filter", &DfiOptions{imageFilter: "nginx"}, []v1.ContainerImage{images[0]}},
+		{"exclude image", &DfiOptions{excludeImage: "nginx|sidecar"}, []v1.ContainerImage{images[1]}},
+		{"min size", &DfiOptions{minSize: "1000"}, []v1.ContainerImage{images[0], images[1]}},
+		{"max size", &DfiOptions{maxSize: "1000"}, []v1.ContainerImage{images[0], images[2]}},
+		{"registry", &DfiOptions{registries: []string{"gcr.io"}}, []v1.ContainerImage{images[1], images[2]}},
+		{"registry and size", &DfiOptions{registries: []string{"gcr.io"}, minSize: "1k"}, []v1.ContainerImage{images[1]}},
+		{"no match", &DfiOptions{registries: []string{"quay.io"}}, []v1.ContainerImage{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			filter, err := test.options.getImageFilter()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			test.options.filter = filter
+
+			actual := test.options.filterImages(images)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestDfiImageFilter(t *testing.T) {
+
+	t.Run("dfi", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			bytes:      true,
+			nocolor:    true,
+			count:      true,
+			registries: []string{"gcr.io"},
+			table:      table.NewOutputTable(buffer),
+		}
+		o.filter, _ = o.getImageFilter()
+
+		if err := o.dfi([]v1.Node{testDescribeNode}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		expected := strings.Join([]string{
+			"NAME        IMAGE USED   ALLOCATABLE   CAPACITY   %USED",
+			"describe1     2500B(2)         8000B     10000B     25%",
+			"",
+		}, "\n")
+
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		o := &DfiOptions{
+			bytes:        true,
+			nocolor:      true,
+			excludeImage: "gcr.io/project/app",
+			table:        table.NewOutputTable(buffer),
+		}
+		o.filter, _ = o.getImageFilter()
+
+		if err := o.listImagesOnNode([]v1.Node{testDescribeNode}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		expected := strings.Join([]string{
+			"NAME        IMAGE SIZE   IMAGE NAME",
+			"describe1        1000B   docker.io/library/nginx:1.17.0",
+			"describe1         500B   gcr.io/project/sidecar:v1",
+			"",
+		}, "\n")
+
+		if buffer.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// findLong defines long description
+	findLong = templates.LongDesc(`
+		Find nodes which have images matching with PATTERN.
+
+		PATTERN is a glob (e.g. "nginx:1.17*") by default, or a regular
+		expression with --regex. PATTERN starting with "sha256:" is a digest
+		and matches with images which have the digest. Glob matches with names
+		without default registry too, so "nginx:*" matches with
+		"docker.io/library/nginx:1.17".
+
+		Kubelet reports at most 50 images (--node-status-max-images) by default.
+		Nodes which report 50 images and no matching image may have matching
+		images, so they are shown as unknown.
+	`)
+
+	// findExample defines command examples
+	findExample = templates.Examples(`
+		# Find nodes which have nginx images.
+		kubectl dfi find 'nginx:*'
+
+		# Find nodes which have images of the digest.
+		kubectl dfi find sha256:3e2f4b
+
+		# Find nodes which do not have images matching with a regular expression.
+		kubectl dfi find --regex 'myapp:v1\.2\.[0-9]+$' --missing
+
+		# Export nodes which have nginx images as csv.
+		kubectl dfi find 'nginx:*' -o csv
+	`)
+)
+
+// FindOptions is struct of find options
+type FindOptions struct {
+	*DfiOptions
+
+	// find options
+	regex   bool
+	missing bool
+}
+
+// NewFindOptions is an instance of FindOptions
+func NewFindOptions(dfi *DfiOptions) *FindOptions {
+	return &FindOptions{
+		DfiOptions: dfi,
+		regex:      false,
+		missing:    false,
+	}
+}
+
+// NewCmdFind is a cobra command wrapping
+func NewCmdFind(dfi *DfiOptions) *cobra.Command {
+	o := NewFindOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "find PATTERN",
+		Short:   "Find nodes which have images matching with pattern.",
+		Long:    findLong,
+		Example: findExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.regex, "regex", "", o.regex, `Treat PATTERN as a regular expression.`)
+	cmd.Flags().BoolVarP(&o.missing, "missing", "", o.missing, `Find nodes which do not have matching images.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *FindOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *FindOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	return o.validateTableOutput()
+}
+
+// Run printing nodes which have (or do not have) matching images
+func (o *FindOptions) Run(args []string) error {
+
+	re, err := newImageMatcher(args[0], o.regex)
+	if err != nil {
+		return err
+	}
+
+	// get nodes
+	nodes, err := o.getNodes([]string{})
+	if err != nil {
+		return err
+	}
+
+	// set printer header
+	o.setTableFormat()
+	if o.missing {
+		o.table.AddColumns([]table.Column{
+			{Name: "NAME", Type: table.String},
+			{Name: "IMAGE USED", Type: table.Bytes},
+			{Name: "CAPACITY", Type: table.Bytes},
+			{Name: "%USED", Type: table.Percent},
+			{Name: "STATUS", Type: table.String},
+		})
+	} else {
+		o.table.AddColumns([]table.Column{
+			{Name: "NAME", Type: table.String},
+			{Name: "IMAGE SIZE", Type: table.Bytes},
+			{Name: "IMAGE NAME", Type: table.String},
+		})
+	}
+
+	truncated := []string{}
+	for _, node := range nodes {
+		images, names := findImages(node.Status.Images, re)
+
+		// matching images may not be reported
+		unknown := len(images) == 0 && isImageListTruncated(node)
+		if unknown {
+			truncated = append(truncated, node.ObjectMeta.Name)
+		}
+
+		if o.missing {
+			if len(images) > 0 {
+				continue
+			}
+			status := "missing"
+			if unknown {
+				status = "unknown"
+			}
+			capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+			used, _ := util.GetImageUsage(node.Status.Images)
+			o.table.AddCells([]table.Cell{
+				{Text: node.ObjectMeta.Name},
+				o.bytesCell(used),
+				o.bytesCell(capacity),
+				o.percentCell(used, capacity, o.warnThreshold, o.critThreshold),
+				{Text: status},
+			})
+			continue
+		}
+
+		if unknown {
+			o.table.AddCells([]table.Cell{{Text: node.ObjectMeta.Name}, {Text: "N/A"}, {Text: "<unknown>"}})
+			continue
+		}
+
+		for i, image := range images {
+			name := names[i]
+			o.table.AddCells([]table.Cell{{Text: node.ObjectMeta.Name}, o.bytesCell(image.SizeBytes), o.imageNameCell(name)})
+		}
+	}
+
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+	o.warnTruncatedNodes(truncated)
+
+	return nil
+}
+
+// newImageMatcher returns regular expression for pattern
+func newImageMatcher(pattern string, regex bool) (*regexp.Regexp, error) {
+
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		return re, nil
+	}
+
+	// digest matches with a part of name
+	if strings.HasPrefix(pattern, "sha256:") {
+		return regexp.MustCompile(regexp.QuoteMeta(pattern)), nil
+	}
+
+	// glob
+	p := regexp.QuoteMeta(pattern)
+	p = strings.Replace(p, `\*`, `.*`, -1)
+	p = strings.Replace(p, `\?`, `.`, -1)
+
+	return regexp.MustCompile("^" + p + "$"), nil
+}
+
+// findImages returns images which have names matching with re, and the matching names
+// Names are also tested without default registry like "docker.io/library/".
+func findImages(images []v1.ContainerImage, re *regexp.Regexp) ([]v1.ContainerImage, []string) {
+
+	found := []v1.ContainerImage{}
+	names := []string{}
+	for _, image := range images {
+		for _, name := range image.Names {
+			short := strings.TrimPrefix(strings.TrimPrefix(name, "docker.io/"), "library/")
+			if re.MatchString(name) || re.MatchString(short) {
+				found = append(found, image)
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	return found, names
+}
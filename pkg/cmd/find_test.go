@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for find
+var testFindNodes = []v1.Node{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{
+					Names:     []string{"docker.io/library/nginx@sha256:3e2f4b", "docker.io/library/nginx:1.17.0"},
+					SizeBytes: 1000,
+				},
+				{
+					Names:     []string{"gcr.io/project/app@sha256:aaaa", "gcr.io/project/app:v1.2.3"},
+					SizeBytes: 2000,
+				},
+			},
+			Capacity: v1.ResourceList{
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{
+					Names:     []string{"docker.io/library/nginx@sha256:9c1a0d", "docker.io/library/nginx:1.16.1"},
+					SizeBytes: 1500,
+				},
+			},
+			Capacity: v1.ResourceList{
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+			},
+		},
+	},
+}
+
+func TestNewFindOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &FindOptions{
+		DfiOptions: dfi,
+		regex:      false,
+		missing:    false,
+	}
+
+	actual := NewFindOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestFindRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		pattern     string
+		regex       bool
+		missing     bool
+		output      string
+		expected    []string
+	}{
+		{
+			"glob",
+			"nginx:*",
+			false,
+			false,
+			"",
+			[]string{
+				"NAME    IMAGE SIZE   IMAGE NAME",
+				"node1        1000B   docker.io/library/nginx:1.17.0",
+				"node2        1500B   docker.io/library/nginx:1.16.1",
+				"node3          N/A   <unknown>",
+				"",
+			},
+		},
+		{
+			"digest",
+			"sha256:9c1a",
+			false,
+			false,
+			"",
+			[]string{
+				"NAME    IMAGE SIZE   IMAGE NAME",
+				"node2        1500B   docker.io/library/nginx@sha256:9c1a0d",
+				"node3          N/A   <unknown>",
+				"",
+			},
+		},
+		{
+			"regex",
+			`app:v1\.2\.[0-9]+$`,
+			true,
+			false,
+			"",
+			[]string{
+				"NAME    IMAGE SIZE   IMAGE NAME",
+				"node1        2000B   gcr.io/project/app:v1.2.3",
+				"node3          N/A   <unknown>",
+				"",
+			},
+		},
+		{
+			"missing",
+			"gcr.io/project/app:*",
+			false,
+			true,
+			"",
+			[]string{
+				"NAME    IMAGE USED   CAPACITY   %USED   STATUS",
+				"node2        1500B     10000B     15%   missing",
+				"node3        5000B     10000B     50%   unknown",
+				"",
+			},
+		},
+		{
+			"csv",
+			"nginx:*",
+			false,
+			false,
+			"csv",
+			[]string{
+				"NAME,IMAGE SIZE,IMAGE NAME",
+				"node1,1000,docker.io/library/nginx:1.17.0",
+				"node2,1500,docker.io/library/nginx:1.16.1",
+				"node3,,<unknown>",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			truncated := testTruncatedNode("node3")
+			fakeClient := fake.NewSimpleClientset(&testFindNodes[0], &testFindNodes[1], &truncated)
+
+			buffer := &bytes.Buffer{}
+			errBuffer := &bytes.Buffer{}
+			o := &FindOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams:     genericclioptions.IOStreams{Out: buffer, ErrOut: errBuffer},
+					bytes:         true,
+					nocolor:       true,
+					warnThreshold: 25,
+					critThreshold: 50,
+					output:        test.output,
+					table:         table.NewOutputTable(buffer),
+					nodeClient:    fakeClient.CoreV1().Nodes(),
+				},
+				regex:   test.regex,
+				missing: test.missing,
+			}
+
+			if err := o.Run([]string{test.pattern}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+
+			// node3 may have matching images
+			if !strings.Contains(errBuffer.String(), "may have images not listed (--node-status-max-images of kubelet): node3") {
+				t.Errorf("[%s] expected warning of node3 (got: %s)", test.description, errBuffer.String())
+			}
+		})
+	}
+}
+
+func TestNewImageMatcher(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		pattern     string
+		regex       bool
+		expected    string
+	}{
+		{"glob", "nginx:1.1?.*", false, `^nginx:1\.1.\..*$`},
+		{"digest", "sha256:3e2f", false, `sha256:3e2f`},
+		{"regex", "nginx:1\\.1[67]", true, `nginx:1\.1[67]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual, err := newImageMatcher(test.pattern, test.regex)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if actual.String() != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual.String(),
+				)
+				return
+			}
+		})
+	}
+
+	t.Run("invalid regex", func(t *testing.T) {
+		if _, err := newImageMatcher("nginx:[", true); err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+}
@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/kubelet"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// gcSimLong defines long description
+	gcSimLong = templates.LongDesc(`
+		Predict images which would be removed by kubelet image garbage collection.
+
+		Image GC runs when usage exceeds imageGCHighThresholdPercent and frees
+		images until usage falls below imageGCLowThresholdPercent. Thresholds
+		are taken from kubelet configuration (/configz) unless --gc-high and
+		--gc-low are given. High threshold of 100 disables image GC, so no
+		images are removed.
+
+		Usage is estimated by total size of images on the node. Images used by
+		pods on the node are never removed. Kubelet removes least recently used
+		images first, but last used time is not exposed through the API, so
+		bigger images are assumed to be removed first. MIN REMOVED is a lower
+		bound of the number of removed images and kubelet may remove more
+		(smaller) images to free TO FREE bytes. Nodes which report 50
+		images may have more unused images than shown, so a warning of them is
+		printed.
+
+		With -o csv or -o tsv, --images prints images to be removed instead of
+		nodes.
+	`)
+
+	// gcSimExample defines command examples
+	gcSimExample = templates.Examples(`
+		# Predict image GC on nodes.
+		kubectl dfi gc-sim
+
+		# Predict image GC with thresholds and show images to be removed.
+		kubectl dfi gc-sim --gc-high 60 --gc-low 50 --images
+
+		# Export images to be removed as csv.
+		kubectl dfi gc-sim --images -o csv
+	`)
+)
+
+// GCSimOptions is struct of gc-sim options
+type GCSimOptions struct {
+	*DfiOptions
+
+	// gc-sim options
+	gcHigh int32
+	gcLow  int32
+	images bool
+
+	// k8s pod client
+	podClient clientv1.PodInterface
+}
+
+// gcResult is result of image GC simulation of a node
+type gcResult struct {
+	node     string
+	used     int64
+	capacity int64
+	high     int32
+	low      int32
+	toFree   int64
+	unused   int64
+	freed    int64
+	removed  []v1.ContainerImage
+}
+
+// NewGCSimOptions is an instance of GCSimOptions
+func NewGCSimOptions(dfi *DfiOptions) *GCSimOptions {
+	return &GCSimOptions{
+		DfiOptions: dfi,
+		gcHigh:     -1,
+		gcLow:      -1,
+		images:     false,
+	}
+}
+
+// NewCmdGCSim is a cobra command wrapping
+func NewCmdGCSim(dfi *DfiOptions) *cobra.Command {
+	o := NewGCSimOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "gc-sim [NODE...]",
+		Short:   "Predict images removed by kubelet image garbage collection.",
+		Long:    gcSimLong,
+		Example: gcSimExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.images, "images", "", o.images, `Show images to be removed.`)
+
+	// int32 options
+	cmd.Flags().Int32VarP(&o.gcHigh, "gc-high", "", o.gcHigh, `Image GC high threshold percent. Taken from kubelet if not set.`)
+	cmd.Flags().Int32VarP(&o.gcLow, "gc-low", "", o.gcLow, `Image GC low threshold percent. Taken from kubelet if not set.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *GCSimOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	o.podClient = o.clientset.CoreV1().Pods(metav1.NamespaceAll)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *GCSimOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if o.gcHigh > 100 || o.gcLow > 100 {
+		return fmt.Errorf("image GC thresholds must be less than or equal to 100 (high:%d low:%d)", o.gcHigh, o.gcLow)
+	}
+
+	if o.gcHigh >= 0 && o.gcLow >= o.gcHigh {
+		return fmt.Errorf("image GC low threshold must be less than high threshold (high:%d low:%d)", o.gcHigh, o.gcLow)
+	}
+
+	return nil
+}
+
+// Run printing prediction of image GC
+func (o *GCSimOptions) Run(args []string) error {
+
+	// get nodes
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	// get pods
+	pl, plerr := o.podClient.List(metav1.ListOptions{})
+	if plerr != nil {
+		return fmt.Errorf("failed to get pods: %v", plerr)
+	}
+	podsOnNode := map[string][]v1.Pod{}
+	for _, pod := range pl.Items {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		podsOnNode[pod.Spec.NodeName] = append(podsOnNode[pod.Spec.NodeName], pod)
+	}
+
+	// thresholds from kubelet are needed only if flags are not set
+	configs := map[string]*kubelet.Config{}
+	if o.gcHigh < 0 || o.gcLow < 0 {
+		configs = o.getKubeletConfigs(nodes)
+	}
+
+	results := []gcResult{}
+	truncated := []string{}
+	for _, node := range nodes {
+		high, low := o.getGCThresholds(configs[node.ObjectMeta.Name])
+		// --gc-low is validated with high threshold of kubelet here
+		if low >= high {
+			return fmt.Errorf("image GC low threshold must be less than high threshold of node %s (high:%d low:%d)", node.ObjectMeta.Name, high, low)
+		}
+		if isImageListTruncated(node) {
+			truncated = append(truncated, node.ObjectMeta.Name)
+		}
+		results = append(results, simulateImageGC(node, podsOnNode[node.ObjectMeta.Name], high, low))
+	}
+	o.warnTruncatedNodes(truncated)
+
+	o.setTableFormat()
+
+	// only images are printed as records
+	if o.images && o.isRecordOutput() {
+		return o.printRemovedImages(o.table, results)
+	}
+
+	// set printer header
+	o.table.AddColumns([]table.Column{
+		{Name: "NAME", Type: table.String},
+		{Name: "IMAGE USED", Type: table.Bytes},
+		{Name: "%USED", Type: table.Percent},
+		{Name: "GC HIGH", Type: table.Percent},
+		{Name: "GC LOW", Type: table.Percent},
+		{Name: "TO FREE", Type: table.Bytes},
+		{Name: "UNUSED", Type: table.Bytes},
+		{Name: "MIN REMOVED", Type: table.Count},
+		{Name: "FREED", Type: table.Bytes},
+	})
+
+	for _, r := range results {
+		row := []table.Cell{
+			{Text: r.node},
+			o.bytesCell(r.used),
+			o.percentCell(r.used, r.capacity, o.warnThreshold, o.critThreshold),
+			thresholdCell(int64(r.high)),
+			thresholdCell(int64(r.low)),
+			o.bytesOrZeroCell(r.toFree),
+			o.bytesOrZeroCell(r.unused),
+			countCell(len(r.removed)),
+			o.bytesOrZeroCell(r.freed),
+		}
+		o.table.AddCells(row)
+	}
+
+	if err := o.table.Print(); err != nil {
+		return err
+	}
+
+	if o.isRecordOutput() {
+		return nil
+	}
+
+	fmt.Fprintln(o.table.Output, "\nMIN REMOVED is a lower bound because bigger images are assumed to be removed first.")
+
+	if !o.images {
+		return nil
+	}
+
+	// images to be removed
+	fmt.Fprintln(o.table.Output)
+	return o.printRemovedImages(o.newSectionTable(), results)
+}
+
+// printRemovedImages prints images to be removed on nodes
+func (o *GCSimOptions) printRemovedImages(t *table.OutputTable, results []gcResult) error {
+
+	t.AddColumns([]table.Column{
+		{Name: "NAME", Type: table.String},
+		{Name: "IMAGE SIZE", Type: table.Bytes},
+		{Name: "IMAGE NAME", Type: table.String},
+	})
+	for _, r := range results {
+		for _, i := range r.removed {
+			name := util.GetImageName(i)
+			t.AddCells([]table.Cell{{Text: r.node}, o.bytesCell(i.SizeBytes), o.imageNameCell(name)})
+		}
+	}
+	return t.Print()
+}
+
+// getGCThresholds returns image GC high and low thresholds
+func (o *GCSimOptions) getGCThresholds(c *kubelet.Config) (int32, int32) {
+
+	if c == nil {
+		c = kubelet.NewConfig()
+	}
+
+	high, low := c.ImageGCHighThresholdPercent, c.ImageGCLowThresholdPercent
+	if o.gcHigh >= 0 {
+		high = o.gcHigh
+	}
+	if o.gcLow >= 0 {
+		low = o.gcLow
+	}
+
+	return high, low
+}
+
+// simulateImageGC predicts images removed by kubelet image GC
+func simulateImageGC(node v1.Node, pods []v1.Pod, high, low int32) gcResult {
+
+	capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+	used, _ := util.GetImageUsage(node.Status.Images)
+
+	r := gcResult{
+		node:     node.ObjectMeta.Name,
+		used:     used,
+		capacity: capacity,
+		high:     high,
+		low:      low,
+	}
+
+	// images in use are not removed
+	inUse := map[int]bool{}
+	for _, pod := range pods {
+		ids := util.GetPodImageIDs(pod)
+		for _, name := range util.GetPodImages(pod) {
+			i := util.FindImageIndex(node.Status.Images, name)
+			if i < 0 && ids.Get(name) != "" {
+				i = util.FindImageIndex(node.Status.Images, ids.Get(name))
+			}
+			if i >= 0 {
+				inUse[i] = true
+			}
+		}
+	}
+
+	unused := []v1.ContainerImage{}
+	for i, image := range node.Status.Images {
+		if !inUse[i] {
+			unused = append(unused, image)
+			r.unused += image.SizeBytes
+		}
+	}
+
+	// GC does not run under high threshold and is disabled by 100
+	if capacity == 0 || high >= 100 || used*100 < capacity*int64(high) {
+		return r
+	}
+
+	r.toFree = used - capacity*int64(low)/100
+
+	// bigger image first
+	sort.SliceStable(unused, func(i, j int) bool {
+		return unused[i].SizeBytes > unused[j].SizeBytes
+	})
+
+	for _, image := range unused {
+		if r.freed >= r.toFree {
+			break
+		}
+		r.removed = append(r.removed, image)
+		r.freed += image.SizeBytes
+	}
+
+	return r
+}
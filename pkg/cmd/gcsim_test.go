@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/kubelet"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test objects for gc-sim
+var (
+	testGCNode = v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcnode"},
+		Status: v1.NodeStatus{
+			Images: []v1.ContainerImage{
+				{Names: []string{"image-a"}, SizeBytes: 5000},
+				{Names: []string{"image-c"}, SizeBytes: 1500},
+				{Names: []string{"image-b"}, SizeBytes: 2000},
+				{Names: []string{"image-d"}, SizeBytes: 500},
+			},
+			Capacity: v1.ResourceList{
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+			},
+		},
+	}
+
+	testGCPods = []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName:   "gcnode",
+				Containers: []v1.Container{{Name: "c1", Image: "image-a"}},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName:   "gcnode",
+				Containers: []v1.Container{{Name: "c1", Image: "image-b"}},
+			},
+			Status: v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	}
+)
+
+func TestNewGCSimOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &GCSimOptions{
+		DfiOptions: dfi,
+		gcHigh:     -1,
+		gcLow:      -1,
+		images:     false,
+	}
+
+	actual := NewGCSimOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestGCSimValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		high        int32
+		low         int32
+		expected    string
+	}{
+		{"not set", -1, -1, ""},
+		{"valid", 80, 70, ""},
+		{"over 100", 101, 70, "image GC thresholds must be less than or equal to 100 (high:101 low:70)"},
+		{"low > high", 70, 80, "image GC low threshold must be less than high threshold (high:70 low:80)"},
+		{"low = high", 70, 70, "image GC low threshold must be less than high threshold (high:70 low:70)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &GCSimOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				gcHigh:     test.high,
+				gcLow:      test.low,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGCSimRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		gcLow       int32
+		expected    []string
+		expectedErr string
+	}{
+		{
+			"table",
+			"",
+			-1,
+			[]string{
+				"NAME     IMAGE USED   %USED   GC HIGH   GC LOW   TO FREE   UNUSED   MIN REMOVED   FREED",
+				"gcnode        9000B     90%       85%      80%     1000B    4000B             1   2000B",
+				"",
+				"MIN REMOVED is a lower bound because bigger images are assumed to be removed first.",
+				"",
+				"NAME     IMAGE SIZE   IMAGE NAME",
+				"gcnode        2000B   image-b",
+				"",
+			},
+			"",
+		},
+		{
+			"csv",
+			"csv",
+			-1,
+			[]string{
+				"NAME,IMAGE SIZE,IMAGE NAME",
+				"gcnode,2000,image-b",
+				"",
+			},
+			"",
+		},
+		{
+			"low over high of kubelet",
+			"",
+			85,
+			[]string{},
+			"image GC low threshold must be less than high threshold of node gcnode (high:85 low:85)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testGCNode, &testGCPods[0], &testGCPods[1])
+
+			buffer := &bytes.Buffer{}
+			o := &GCSimOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams:     genericclioptions.IOStreams{ErrOut: &bytes.Buffer{}},
+					bytes:         true,
+					nocolor:       true,
+					output:        test.output,
+					table:         table.NewOutputTable(buffer),
+					nodeClient:    fakeClient.CoreV1().Nodes(),
+					configzGetter: testConfigzGetter,
+				},
+				gcHigh:    -1,
+				gcLow:     test.gcLow,
+				images:    true,
+				podClient: fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+			}
+
+			err := o.Run([]string{})
+			if (err == nil && test.expectedErr != "") || (err != nil && err.Error() != test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestGCSimRunTruncated(t *testing.T) {
+
+	node := testTruncatedNode("big")
+	fakeClient := fake.NewSimpleClientset(&node)
+
+	errBuffer := &bytes.Buffer{}
+	o := &GCSimOptions{
+		DfiOptions: &DfiOptions{
+			IOStreams:  genericclioptions.IOStreams{ErrOut: errBuffer},
+			bytes:      true,
+			nocolor:    true,
+			table:      table.NewOutputTable(&bytes.Buffer{}),
+			nodeClient: fakeClient.CoreV1().Nodes(),
+		},
+		gcHigh:    85,
+		gcLow:     80,
+		podClient: fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+	}
+
+	if err := o.Run([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := "warning: 1 node(s) report 50 or more images and may have images not listed (--node-status-max-images of kubelet): big\n"
+	if errBuffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, errBuffer.String())
+	}
+}
+
+func TestGetGCThresholds(t *testing.T) {
+
+	var tests = []struct {
+		description  string
+		high         int32
+		low          int32
+		config       *kubelet.Config
+		expectedHigh int32
+		expectedLow  int32
+	}{
+		{"defaults", -1, -1, nil, 85, 80},
+		{"kubelet", -1, -1, &kubelet.Config{ImageGCHighThresholdPercent: 70, ImageGCLowThresholdPercent: 60}, 70, 60},
+		{"flags", 50, 40, &kubelet.Config{ImageGCHighThresholdPercent: 70, ImageGCLowThresholdPercent: 60}, 50, 40},
+		{"high flag only", 75, -1, nil, 75, 80},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &GCSimOptions{gcHigh: test.high, gcLow: test.low}
+			high, low := o.getGCThresholds(test.config)
+			if high != test.expectedHigh || low != test.expectedLow {
+				t.Errorf(
+					"[%s] expected(%d, %d) differ (got: %d, %d)",
+					test.description,
+					test.expectedHigh,
+					test.expectedLow,
+					high,
+					low,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestSimulateImageGC(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		high        int32
+		low         int32
+		expected    gcResult
+	}{
+		{
+			"under high threshold",
+			95,
+			50,
+			gcResult{node: "gcnode", used: 9000, capacity: 10000, high: 95, low: 50, unused: 4000},
+		},
+		{
+			"GC disabled",
+			100,
+			0,
+			gcResult{node: "gcnode", used: 9000, capacity: 10000, high: 100, low: 0, unused: 4000},
+		},
+		{
+			"remove bigger images first",
+			85,
+			50,
+			gcResult{
+				node:     "gcnode",
+				used:     9000,
+				capacity: 10000,
+				high:     85,
+				low:      50,
+				toFree:   4000,
+				unused:   4000,
+				freed:    4000,
+				removed: []v1.ContainerImage{
+					{Names: []string{"image-b"}, SizeBytes: 2000},
+					{Names: []string{"image-c"}, SizeBytes: 1500},
+					{Names: []string{"image-d"}, SizeBytes: 500},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := simulateImageGC(testGCNode, testGCPods[:1], test.high, test.low)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
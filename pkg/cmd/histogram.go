@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// histogramLong defines long description
+	histogramLong = templates.LongDesc(`
+		Show histogram of image sizes across nodes.
+
+		Images are bucketed by size. IMAGES is number of unique images and
+		COPIES is number of images on nodes. TOTAL is disk usage of the copies.
+	`)
+
+	// histogramExample defines command examples
+	histogramExample = templates.Examples(`
+		# Show histogram of image sizes.
+		kubectl dfi histogram
+
+		# Show histogram with custom buckets.
+		kubectl dfi histogram --buckets 50Mi,200Mi,1Gi
+
+		# Show histogram of images in gcr.io.
+		kubectl dfi histogram --registry gcr.io
+	`)
+)
+
+// HistogramOptions is struct of histogram options
+type HistogramOptions struct {
+	*DfiOptions
+
+	// histogram options
+	buckets []string
+}
+
+// sizeBucket is images in a range of size
+type sizeBucket struct {
+	label  string
+	images int
+	copies int
+	bytes  int64
+}
+
+// NewHistogramOptions is an instance of HistogramOptions
+func NewHistogramOptions(dfi *DfiOptions) *HistogramOptions {
+	return &HistogramOptions{
+		DfiOptions: dfi,
+		buckets:    []string{"100Mi", "500Mi", "1Gi", "5Gi"},
+	}
+}
+
+// NewCmdHistogram is a cobra command wrapping
+func NewCmdHistogram(dfi *DfiOptions) *cobra.Command {
+	o := NewHistogramOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "histogram [NODE...]",
+		Short:   "Show histogram of image sizes across nodes.",
+		Long:    histogramLong,
+		Example: histogramExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// string option
+	cmd.Flags().StringSliceVarP(&o.buckets, "buckets", "", o.buckets, `Upper bounds of buckets in ascending order.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	// image filter options
+	o.addImageFilterFlags(cmd.Flags())
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *HistogramOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *HistogramOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if _, err := o.getBucketSizes(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run printing histogram of image sizes
+func (o *HistogramOptions) Run(args []string) error {
+
+	sizes, err := o.getBucketSizes()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+	for i := range nodes {
+		nodes[i].Status.Images = o.filterImages(nodes[i].Status.Images)
+	}
+
+	buckets := getSizeBuckets(nodes, sizes, o.buckets)
+
+	var total int64
+	var max int
+	for _, b := range buckets {
+		total += b.bytes
+		if b.images > max {
+			max = b.images
+		}
+	}
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: "SIZE", Type: table.String},
+		{Name: "IMAGES", Type: table.Count},
+		{Name: "COPIES", Type: table.Count},
+		{Name: "TOTAL", Type: table.Bytes},
+		{Name: "%TOTAL", Type: table.Percent},
+		{Name: "HISTOGRAM", Type: table.String},
+	})
+
+	for _, b := range buckets {
+		percent := countCell(int(getPercent(b.bytes, total)))
+		percent.Text += "%"
+		row := []table.Cell{
+			{Text: b.label},
+			countCell(b.images),
+			countCell(b.copies),
+			o.bytesCell(b.bytes),
+			percent,
+			{Text: drawBar(int64(b.images), int64(max), barWidth)},
+		}
+		o.table.AddCells(row)
+	}
+
+	return o.table.Print()
+}
+
+// getBucketSizes returns upper bounds of buckets in bytes
+func (o *HistogramOptions) getBucketSizes() ([]int64, error) {
+
+	if len(o.buckets) == 0 {
+		return nil, fmt.Errorf("at least one bucket is required")
+	}
+
+	sizes := []int64{}
+	for _, b := range o.buckets {
+		q, err := resource.ParseQuantity(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket: %s", b)
+		}
+
+		size := q.Value()
+		if len(sizes) > 0 && size <= sizes[len(sizes)-1] {
+			return nil, fmt.Errorf("buckets must be in ascending order: %s", strings.Join(o.buckets, ","))
+		}
+		sizes = append(sizes, size)
+	}
+
+	return sizes, nil
+}
+
+// getSizeBuckets returns images bucketed by size
+// The last bucket has images larger than or equal to the largest bound.
+// Unique images are bucketed by the biggest size on nodes.
+func getSizeBuckets(nodes []v1.Node, sizes []int64, labels []string) []sizeBucket {
+
+	buckets := []sizeBucket{}
+	for _, l := range labels {
+		buckets = append(buckets, sizeBucket{label: "<" + l})
+	}
+	buckets = append(buckets, sizeBucket{label: ">=" + labels[len(labels)-1]})
+
+	index := func(size int64) int {
+		for i, s := range sizes {
+			if size < s {
+				return i
+			}
+		}
+		return len(sizes)
+	}
+
+	for _, image := range getImageSet(nodes) {
+		buckets[index(image.SizeBytes)].images++
+	}
+
+	for _, node := range nodes {
+		for _, image := range node.Status.Images {
+			b := &buckets[index(image.SizeBytes)]
+			b.copies++
+			b.bytes += image.SizeBytes
+		}
+	}
+
+	return buckets
+}
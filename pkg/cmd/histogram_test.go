@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+func TestNewHistogramOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &HistogramOptions{
+		DfiOptions: dfi,
+		buckets:    []string{"100Mi", "500Mi", "1Gi", "5Gi"},
+	}
+
+	actual := NewHistogramOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestHistogramValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		buckets     []string
+		output      string
+		expected    string
+	}{
+		{"default", []string{"100Mi", "500Mi", "1Gi", "5Gi"}, "", ""},
+		{"csv", []string{"1Gi"}, "csv", ""},
+		{"no bucket", []string{}, "", "at least one bucket is required"},
+		{"invalid bucket", []string{"foo"}, "", "invalid bucket: foo"},
+		{"not ascending", []string{"1Gi", "500Mi"}, "", "buckets must be in ascending order: 1Gi,500Mi"},
+		{"wide output", []string{"1Gi"}, "wide", "invalid output: wide (valid values: csv, tsv, markdown)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &HistogramOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50, output: test.output},
+				buckets:    test.buckets,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestHistogramImageFilterFlags(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	cmd := NewCmdHistogram(dfi)
+
+	if err := cmd.Flags().Parse([]string{"--min-size", "1Gi", "--registry", "gcr.io"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	o := &HistogramOptions{DfiOptions: dfi, buckets: []string{"1Gi"}}
+	if err := o.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if o.filter == nil || o.filter.minSize != 1<<30 || !reflect.DeepEqual(o.filter.registries, []string{"gcr.io"}) {
+		t.Errorf("expected image filter of flags (got: %#v)", o.filter)
+	}
+}
+
+func TestHistogramRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		expected    []string
+	}{
+		{
+			"table",
+			"",
+			[]string{
+				"SIZE     IMAGES   COPIES   TOTAL   %TOTAL   HISTOGRAM",
+				"<2000         1        3   3200B      24%   [##########..........]",
+				"<4000         2        2   5000B      37%   [####################]",
+				">=4000        1        1   5000B      37%   [##########..........]",
+				"",
+			},
+		},
+		{
+			"csv",
+			"csv",
+			[]string{
+				"SIZE,IMAGES,COPIES,TOTAL,%TOTAL,HISTOGRAM",
+				"<2000,1,3,3200,24,[##########..........]",
+				"<4000,2,2,5000,37,[####################]",
+				">=4000,1,1,5000,37,[##########..........]",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testCompareNodes[0], &testCompareNodes[1], &testCompareNodes[2])
+
+			buffer := &bytes.Buffer{}
+			o := &HistogramOptions{
+				DfiOptions: &DfiOptions{
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				buckets: []string{"2000", "4000"},
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestGetSizeBuckets(t *testing.T) {
+
+	expected := []sizeBucket{
+		{label: "<2000", images: 1, copies: 3, bytes: 3200},
+		{label: "<4000", images: 2, copies: 2, bytes: 5000},
+		{label: ">=4000", images: 1, copies: 1, bytes: 5000},
+	}
+
+	actual := getSizeBuckets(testCompareNodes, []int64{2000, 4000}, []string{"2000", "4000"})
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
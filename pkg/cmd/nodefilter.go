@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"path"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// valid values for node filters
+var (
+	nodeRoles = []string{"control-plane", "worker"}
+	nodeOSes  = []string{"linux", "windows"}
+)
+
+// labels of control plane nodes
+var controlPlaneLabels = []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"}
+
+// isNodePattern returns true if arg is a glob pattern of node names
+func isNodePattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// matchNodePatterns returns true if name matches with any pattern
+func matchNodePatterns(name string, patterns []string) bool {
+
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNode returns true if node satisfies all node filters
+func (o *DfiOptions) matchNode(node v1.Node) bool {
+
+	if o.readyOnly && !isNodeReady(node) {
+		return false
+	}
+
+	if o.schedulableOnly && node.Spec.Unschedulable {
+		return false
+	}
+
+	if o.role != "" && getNodeRole(node) != o.role {
+		return false
+	}
+
+	if o.operatingSystem != "" && getNodeLabel(node, "kubernetes.io/os", node.Status.NodeInfo.OperatingSystem) != o.operatingSystem {
+		return false
+	}
+
+	if o.arch != "" && getNodeLabel(node, "kubernetes.io/arch", node.Status.NodeInfo.Architecture) != o.arch {
+		return false
+	}
+
+	return true
+}
+
+// isNodeReady returns true if Ready condition of node is True
+func isNodeReady(node v1.Node) bool {
+
+	for _, c := range node.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getNodeRole returns "control-plane" or "worker"
+func getNodeRole(node v1.Node) string {
+
+	for _, l := range controlPlaneLabels {
+		if _, ok := node.ObjectMeta.Labels[l]; ok {
+			return "control-plane"
+		}
+	}
+	return "worker"
+}
+
+// getNodeLabel returns value of well-known label
+// Deprecated "beta." label and fallback are used if the label is not set.
+func getNodeLabel(node v1.Node, key, fallback string) string {
+
+	if v, ok := node.ObjectMeta.Labels[key]; ok {
+		return v
+	}
+	if v, ok := node.ObjectMeta.Labels["beta."+key]; ok {
+		return v
+	}
+	return fallback
+}
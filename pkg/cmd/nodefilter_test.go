@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// test objects for node filters
+var testFilterNodes = []v1.Node{
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "master1",
+			Labels: map[string]string{"node-role.kubernetes.io/master": "", "kubernetes.io/os": "linux", "kubernetes.io/arch": "amd64"},
+		},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "linux1",
+			Labels: map[string]string{"beta.kubernetes.io/os": "linux", "beta.kubernetes.io/arch": "arm64"},
+		},
+		Spec: v1.NodeSpec{Unschedulable: true},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "windows1"},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}},
+			NodeInfo:   v1.NodeSystemInfo{OperatingSystem: "windows", Architecture: "amd64"},
+		},
+	},
+}
+
+func TestValidateNodeFilters(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		role        string
+		os          string
+		expected    string
+	}{
+		{"no filter", "", "", ""},
+		{"valid", "worker", "windows", ""},
+		{"invalid role", "master", "", "invalid role: master (valid values: control-plane, worker)"},
+		{"invalid os", "", "darwin", "invalid os: darwin (valid values: linux, windows)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &DfiOptions{
+				warnThreshold:   25,
+				critThreshold:   50,
+				role:            test.role,
+				operatingSystem: test.os,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetNodesWithFilters(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		args        []string
+		options     *DfiOptions
+		expected    []string
+	}{
+		{"no filter", []string{}, &DfiOptions{}, []string{"master1", "linux1", "windows1"}},
+		{"ready only", []string{}, &DfiOptions{readyOnly: true}, []string{"master1", "linux1"}},
+		{"schedulable only", []string{}, &DfiOptions{schedulableOnly: true}, []string{"master1", "windows1"}},
+		{"control plane", []string{}, &DfiOptions{role: "control-plane"}, []string{"master1"}},
+		{"worker", []string{}, &DfiOptions{role: "worker"}, []string{"linux1", "windows1"}},
+		{"linux", []string{}, &DfiOptions{operatingSystem: "linux"}, []string{"master1", "linux1"}},
+		{"windows", []string{}, &DfiOptions{operatingSystem: "windows"}, []string{"windows1"}},
+		{"amd64", []string{}, &DfiOptions{arch: "amd64"}, []string{"master1", "windows1"}},
+		{"pattern", []string{"*1", "[lw]*"}, &DfiOptions{readyOnly: true}, []string{"master1", "linux1"}},
+		{"filtered node name", []string{"windows1"}, &DfiOptions{operatingSystem: "linux"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testFilterNodes[0], &testFilterNodes[1], &testFilterNodes[2])
+			test.options.nodeClient = fakeClient.CoreV1().Nodes()
+
+			nodes, err := test.options.getNodes(test.args)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			actual := []string{}
+			for _, n := range nodes {
+				actual = append(actual, n.ObjectMeta.Name)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
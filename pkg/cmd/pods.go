@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// podsLong defines long description
+	podsLong = templates.LongDesc(`
+		Show images of pods and disk usage of them on Kubernetes nodes.
+	`)
+
+	// podsExample defines command examples
+	podsExample = templates.Examples(`
+		# Show image usage of pods in current namespace.
+		kubectl dfi pods
+
+		# Show image usage of pods in all namespaces.
+		kubectl dfi pods -A
+
+		# Sort pods by node name.
+		kubectl dfi pods --sort-by node
+
+		# Export image usage of pods as csv.
+		kubectl dfi pods -A -o csv
+	`)
+
+	// podsSortKeys defines valid keys for --sort-by
+	podsSortKeys = []string{"size", "name", "namespace", "node"}
+)
+
+// PodsOptions is struct of pods options
+type PodsOptions struct {
+	*DfiOptions
+
+	// pods options
+	namespace     string
+	allNamespaces bool
+	sortBy        string
+
+	// k8s pod client
+	podClient clientv1.PodInterface
+}
+
+// podImageUsage is image usage of a pod
+type podImageUsage struct {
+	namespace string
+	name      string
+	node      string
+	images    []string
+	total     int64
+}
+
+// NewPodsOptions is an instance of PodsOptions
+func NewPodsOptions(dfi *DfiOptions) *PodsOptions {
+	return &PodsOptions{
+		DfiOptions:    dfi,
+		allNamespaces: false,
+		sortBy:        "size",
+	}
+}
+
+// NewCmdPods is a cobra command wrapping
+func NewCmdPods(dfi *DfiOptions) *cobra.Command {
+	o := NewPodsOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "pods [NAME...]",
+		Short:   "Show images of pods and disk usage of them.",
+		Long:    podsLong,
+		Example: podsExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "A", o.allNamespaces, `List pods across all namespaces.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+	cmd.Flags().StringVarP(&o.sortBy, "sort-by", "", o.sortBy, `Sort pods by one of `+strings.Join(podsSortKeys, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *PodsOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	ns, err := o.getNamespace(o.allNamespaces)
+	if err != nil {
+		return err
+	}
+	o.namespace = ns
+
+	o.podClient = o.clientset.CoreV1().Pods(o.namespace)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *PodsOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if !containsString(podsSortKeys, o.sortBy) {
+		return fmt.Errorf("invalid sort key: %s (valid keys: %s)", o.sortBy, strings.Join(podsSortKeys, ", "))
+	}
+
+	return nil
+}
+
+// Run printing images of pods
+func (o *PodsOptions) Run(args []string) error {
+
+	// get pods
+	pl, plerr := o.podClient.List(metav1.ListOptions{LabelSelector: o.labelSelector})
+	if plerr != nil {
+		return fmt.Errorf("failed to get pods: %v", plerr)
+	}
+
+	// get images on nodes
+	// --selector selects pods, so nodes are filtered only by node filters.
+	nodes, err := o.getNodesWithoutSelector()
+	if err != nil {
+		return err
+	}
+	images := map[string][]v1.ContainerImage{}
+	for _, node := range nodes {
+		images[node.ObjectMeta.Name] = node.Status.Images
+	}
+
+	// pod loop
+	usages := []podImageUsage{}
+	for _, pod := range pl.Items {
+
+		// filter by args
+		if len(args) > 0 && !containsString(args, pod.ObjectMeta.Name) {
+			continue
+		}
+
+		// skip pods on nodes filtered out
+		if _, ok := images[pod.Spec.NodeName]; pod.Spec.NodeName != "" && !ok {
+			continue
+		}
+
+		usages = append(usages, o.getPodImageUsage(pod, images[pod.Spec.NodeName]))
+	}
+
+	o.sortPodImageUsages(usages)
+
+	// set printer header
+	columns := []table.Column{
+		{Name: "NAME", Type: table.String},
+		{Name: "NODE", Type: table.String},
+		{Name: "IMAGE TOTAL", Type: table.Bytes},
+		{Name: "IMAGES", Type: table.String},
+	}
+	if o.allNamespaces {
+		columns = append([]table.Column{{Name: "NAMESPACE", Type: table.String}}, columns...)
+	}
+	o.setTableFormat()
+	o.table.AddColumns(columns)
+
+	for _, u := range usages {
+
+		node := u.node
+		if node == "" {
+			node = "<none>"
+		}
+
+		images := table.Cell{Text: strings.Join(u.images, ",")}
+		if !o.nocolor {
+			images.Color = colorPodImageTags
+		}
+
+		row := []table.Cell{{Text: u.name}, {Text: node}, o.bytesCell(u.total), images}
+		if o.allNamespaces {
+			row = append([]table.Cell{{Text: u.namespace}}, row...)
+		}
+		o.table.AddCells(row)
+	}
+
+	return o.table.Print()
+}
+
+// getPodImageUsage sums up size of images which are used by pod
+func (o *PodsOptions) getPodImageUsage(pod v1.Pod, nodeImages []v1.ContainerImage) podImageUsage {
+
+	u := podImageUsage{
+		namespace: pod.ObjectMeta.Namespace,
+		name:      pod.ObjectMeta.Name,
+		node:      pod.Spec.NodeName,
+	}
+
+	ids := util.GetPodImageIDs(pod)
+	for _, name := range util.GetPodImages(pod) {
+
+		// fallback to image id (digest) if tag is not found on node
+		image, found := util.FindImage(nodeImages, name)
+		if !found && ids.Get(name) != "" {
+			image, found = util.FindImage(nodeImages, ids.Get(name))
+		}
+
+		var size int64
+		if found {
+			size = image.SizeBytes
+			u.total += size
+		}
+
+		u.images = append(u.images, name+"("+o.toUnit(size)+")")
+	}
+
+	return u
+}
+
+// sortPodImageUsages sorts usages by sort key
+func (o *PodsOptions) sortPodImageUsages(usages []podImageUsage) {
+	sort.SliceStable(usages, func(i, j int) bool {
+		switch o.sortBy {
+		case "name":
+			return usages[i].name < usages[j].name
+		case "namespace":
+			if usages[i].namespace != usages[j].namespace {
+				return usages[i].namespace < usages[j].namespace
+			}
+			return usages[i].name < usages[j].name
+		case "node":
+			if usages[i].node != usages[j].node {
+				return usages[i].node < usages[j].node
+			}
+			return usages[i].name < usages[j].name
+		default:
+			return usages[i].total > usages[j].total
+		}
+	})
+}
+
+// containsString reports whether s is within list
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// colorPodImageTags colors tags of "name(size)" images joined with comma
+func colorPodImageTags(text string) string {
+
+	images := strings.Split(text, ",")
+	for i, image := range images {
+		size := ""
+		if p := strings.LastIndex(image, "("); p >= 0 {
+			image, size = image[:p], image[p:]
+		}
+		images[i] = util.RenderImageTag(image) + size
+	}
+	return strings.Join(images, ",")
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	color "github.com/gookit/color"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test pod object
+var testPods = []v1.Pod{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			InitContainers: []v1.Container{
+				{Name: "init", Image: "image2"},
+			},
+			Containers: []v1.Container{
+				{Name: "c1", Image: "image2"},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "kube-system"},
+		Spec: v1.PodSpec{
+			NodeName: "node2",
+			Containers: []v1.Container{
+				{Name: "c1", Image: "image1"},
+				{Name: "c2", Image: "image3"},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "c1", Image: "image1"},
+			},
+		},
+	},
+}
+
+func TestNewPodsOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &PodsOptions{
+		DfiOptions:    dfi,
+		allNamespaces: false,
+		sortBy:        "size",
+	}
+
+	actual := NewPodsOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestPodsValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		sortBy      string
+		output      string
+		expected    string
+	}{
+		{"size", "size", "", ""},
+		{"node", "node", "", ""},
+		{"csv", "size", "csv", ""},
+		{"invalid", "foo", "", "invalid sort key: foo (valid keys: size, name, namespace, node)"},
+		{"wide output", "size", "wide", "invalid output: wide (valid values: csv, tsv, markdown)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &PodsOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50, output: test.output},
+				sortBy:     test.sortBy,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestPodsRun(t *testing.T) {
+
+	var tests = []struct {
+		description   string
+		args          []string
+		allNamespaces bool
+		sortBy        string
+		readyOnly     bool
+		expected      []string
+	}{
+		{
+			"sort by size",
+			[]string{},
+			true,
+			"size",
+			false,
+			[]string{
+				"NAMESPACE     NAME   NODE     IMAGE TOTAL   IMAGES",
+				"kube-system   pod2   node2             2K   image1(2K),image3(N/A)",
+				"default       pod1   node1             1K   image2(1K)",
+				"default       pod3   <none>           N/A   image1(N/A)",
+				"",
+			},
+		},
+		{
+			"sort by namespace",
+			[]string{},
+			true,
+			"namespace",
+			false,
+			[]string{
+				"NAMESPACE     NAME   NODE     IMAGE TOTAL   IMAGES",
+				"default       pod1   node1             1K   image2(1K)",
+				"default       pod3   <none>           N/A   image1(N/A)",
+				"kube-system   pod2   node2             2K   image1(2K),image3(N/A)",
+				"",
+			},
+		},
+		{
+			"filter by args",
+			[]string{"pod1"},
+			false,
+			"size",
+			false,
+			[]string{
+				"NAME   NODE    IMAGE TOTAL   IMAGES",
+				"pod1   node1            1K   image2(1K)",
+				"",
+			},
+		},
+		{
+			"skip pods on nodes filtered out",
+			[]string{},
+			true,
+			"size",
+			true,
+			[]string{
+				"NAMESPACE   NAME   NODE     IMAGE TOTAL   IMAGES",
+				"default     pod3   <none>           N/A   image1(N/A)",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1], &testPods[0], &testPods[1], &testPods[2])
+
+			buffer := &bytes.Buffer{}
+			o := &PodsOptions{
+				DfiOptions: &DfiOptions{
+					nocolor:    true,
+					readyOnly:  test.readyOnly,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				allNamespaces: test.allNamespaces,
+				sortBy:        test.sortBy,
+				podClient:     fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+			}
+
+			if err := o.Run(test.args); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestGetPodImageUsage(t *testing.T) {
+
+	o := &PodsOptions{
+		DfiOptions: &DfiOptions{nocolor: true},
+	}
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			Containers: []v1.Container{
+				{Name: "c1", Image: "myimage:v1"},
+			},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{Image: "myimage:v1", ImageID: "docker-pullable://myimage@sha256:abcd"},
+			},
+		},
+	}
+	images := []v1.ContainerImage{
+		{Names: []string{"myimage@sha256:abcd"}, SizeBytes: 3000},
+	}
+
+	expected := podImageUsage{
+		namespace: "default",
+		name:      "pod1",
+		node:      "node1",
+		images:    []string{"myimage:v1(3K)"},
+		total:     3000,
+	}
+
+	actual := o.getPodImageUsage(pod, images)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestColorPodImageTags(t *testing.T) {
+
+	yellow := color.FgYellow.Render
+
+	var tests = []struct {
+		description string
+		text        string
+		expected    string
+	}{
+		{"images", "nginx:1.17(1K),busybox(2K)", "nginx:" + yellow("1.17") + "(1K),busybox(2K)"},
+		{"registry port", "reg:5000/app:v1(N/A)", "reg:5000/app:" + yellow("v1") + "(N/A)"},
+		{"truncated", "nginx:1...ox:v1(2K)", "nginx:1...ox:" + yellow("v1") + "(2K)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := colorPodImageTags(test.text)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientbatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// prepullLong defines long description
+	prepullLong = templates.LongDesc(`
+		Generate DaemonSets or Jobs to pre-pull images on nodes which do not have them.
+
+		Nodes are skipped if pulling the image makes usage exceed the crit
+		threshold. Image size is taken from other nodes. Images are pulled by
+		init containers (DaemonSet) or containers (Job) which run "sh -c true",
+		so pulled images should have a shell. Images without a shell (e.g.
+		distroless or scratch based images) are pulled, but their containers
+		fail to start. Generated manifests are printed as YAML, or created
+		with --apply.
+
+		Images of --filename are taken from pods and pod templates of
+		workloads. Items of List are expanded, and other kinds (e.g. custom
+		resources) are skipped.
+	`)
+
+	// prepullExample defines command examples
+	prepullExample = templates.Examples(`
+		# Generate DaemonSets to pre-pull images.
+		kubectl dfi prepull --image nginx:1.17 --image redis:5
+
+		# Generate Jobs to pre-pull images in manifest on nodes in pool-a.
+		kubectl dfi prepull -f deployment.yaml --mode job -l pool=a
+
+		# Create DaemonSets to pre-pull images.
+		kubectl dfi prepull --image nginx:1.17 --apply
+	`)
+
+	// prepullModes defines valid values for --mode
+	prepullModes = []string{"daemonset", "job"}
+
+	// invalidNameChars matches characters which can not be used in name
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// prepullLabels are labels of generated resources
+var prepullLabels = map[string]string{"app.kubernetes.io/managed-by": "kubectl-dfi"}
+
+// PrepullOptions is struct of prepull options
+type PrepullOptions struct {
+	*DfiOptions
+
+	// prepull options
+	images     []string
+	filename   string
+	mode       string
+	pauseImage string
+	apply      bool
+	namespace  string
+
+	// k8s clients
+	daemonSetClient clientappsv1.DaemonSetInterface
+	jobClient       clientbatchv1.JobInterface
+}
+
+// prepullPlan is nodes which need an image
+type prepullPlan struct {
+	image      string
+	size       int64
+	nodes      []string
+	noHeadroom []string
+}
+
+// NewPrepullOptions is an instance of PrepullOptions
+func NewPrepullOptions(dfi *DfiOptions) *PrepullOptions {
+	return &PrepullOptions{
+		DfiOptions: dfi,
+		images:     []string{},
+		filename:   "",
+		mode:       "daemonset",
+		pauseImage: "k8s.gcr.io/pause:3.1",
+		apply:      false,
+	}
+}
+
+// NewCmdPrepull is a cobra command wrapping
+func NewCmdPrepull(dfi *DfiOptions) *cobra.Command {
+	o := NewPrepullOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "prepull [NODE...]",
+		Short:   "Generate DaemonSets or Jobs to pre-pull images on nodes.",
+		Long:    prepullLong,
+		Example: prepullExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.apply, "apply", "", o.apply, `Create generated resources instead of printing them.`)
+
+	// string option
+	cmd.Flags().StringSliceVarP(&o.images, "image", "", o.images, `Image to pre-pull. Can be specified multiple times.`)
+	cmd.Flags().StringVarP(&o.filename, "filename", "f", o.filename, `Manifest file which has images to pre-pull.`)
+	cmd.Flags().StringVarP(&o.mode, "mode", "", o.mode, `Kind of generated resources. One of `+strings.Join(prepullModes, "|")+`.`)
+	cmd.Flags().StringVarP(&o.pauseImage, "pause-image", "", o.pauseImage, `Image of main container of DaemonSet.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *PrepullOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	ns, err := o.getNamespace(false)
+	if err != nil {
+		return err
+	}
+	o.namespace = ns
+
+	o.daemonSetClient = o.clientset.AppsV1().DaemonSets(o.namespace)
+	o.jobClient = o.clientset.BatchV1().Jobs(o.namespace)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *PrepullOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.images) == 0 && o.filename == "" {
+		return fmt.Errorf("--image or --filename is required")
+	}
+
+	if !containsString(prepullModes, o.mode) {
+		return fmt.Errorf("invalid mode: %s (valid values: %s)", o.mode, strings.Join(prepullModes, ", "))
+	}
+
+	return nil
+}
+
+// Run printing or creating resources to pre-pull images
+func (o *PrepullOptions) Run(args []string) error {
+
+	images := append([]string{}, o.images...)
+	if o.filename != "" {
+		b, err := ioutil.ReadFile(o.filename)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %v", err)
+		}
+		mi, err := getManifestImages(b)
+		if err != nil {
+			return err
+		}
+		images = append(images, mi...)
+	}
+
+	// get nodes
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	// sizes are taken from nodes not selected by args and --selector too
+	known := nodes
+	if len(args) > 0 || o.labelSelector != "" {
+		if known, err = o.getNodesWithoutSelector(); err != nil {
+			return err
+		}
+	}
+
+	plans := o.getPrepullPlans(images, nodes, getKnownImageSizes(known))
+	if err := o.printPrepullPlans(plans); err != nil {
+		return err
+	}
+
+	objects := []runtime.Object{}
+	if o.mode == "job" {
+		for _, j := range o.newPrepullJobs(plans) {
+			objects = append(objects, j)
+		}
+	} else {
+		for _, ds := range o.newPrepullDaemonSets(plans) {
+			objects = append(objects, ds)
+		}
+	}
+
+	if o.apply {
+		return o.createPrepullObjects(objects)
+	}
+
+	for i, obj := range objects {
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(o.Out, "---")
+		}
+		fmt.Fprint(o.Out, string(b))
+	}
+
+	return nil
+}
+
+// getPrepullPlans returns nodes which do not have images and have headroom
+func (o *PrepullOptions) getPrepullPlans(images []string, nodes []v1.Node, sizes map[string]int64) []prepullPlan {
+
+	// planned is bytes of images planned to pull on each node
+	// Usage is checked with all planned images, not only with each image.
+	planned := map[string]int64{}
+
+	plans := []prepullPlan{}
+	seen := map[string]bool{}
+	for _, image := range images {
+
+		n := util.NormalizeImageName(image)
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+
+		p := prepullPlan{image: image, size: sizes[n]}
+		for _, node := range nodes {
+			if _, found := util.FindImage(node.Status.Images, image); found {
+				continue
+			}
+
+			// usage after pull must be under crit threshold
+			capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+			used, _ := util.GetImageUsage(node.Status.Images)
+			if capacity > 0 && (used+planned[node.ObjectMeta.Name]+p.size)*100 >= capacity*o.critThreshold {
+				p.noHeadroom = append(p.noHeadroom, node.ObjectMeta.Name)
+				continue
+			}
+
+			planned[node.ObjectMeta.Name] += p.size
+			p.nodes = append(p.nodes, node.ObjectMeta.Name)
+		}
+
+		plans = append(plans, p)
+	}
+
+	return plans
+}
+
+// printPrepullPlans prints plans to stderr not to mix with manifests
+func (o *PrepullOptions) printPrepullPlans(plans []prepullPlan) error {
+
+	t := table.NewOutputTable(o.ErrOut)
+	t.AddHeader([]string{"NAME", "IMAGE SIZE", "IMAGE NAME", "STATUS"})
+	for _, p := range plans {
+		for _, n := range p.nodes {
+			t.AddRow([]string{n, o.toUnit(p.size), p.image, "pull"})
+		}
+		for _, n := range p.noHeadroom {
+			t.AddRow([]string{n, o.toUnit(p.size), p.image, "no headroom"})
+		}
+	}
+	return t.Print()
+}
+
+// newPrepullDaemonSets returns a DaemonSet for each image
+func (o *PrepullOptions) newPrepullDaemonSets(plans []prepullPlan) []*appsv1.DaemonSet {
+
+	dss := []*appsv1.DaemonSet{}
+	for _, p := range plans {
+		if len(p.nodes) == 0 {
+			continue
+		}
+
+		name := getPrepullName(p.image)
+		labels := map[string]string{"app": name}
+		for k, v := range prepullLabels {
+			labels[k] = v
+		}
+
+		dss = append(dss, &appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: o.namespace, Labels: labels},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: v1.PodSpec{
+						Affinity: &v1.Affinity{
+							NodeAffinity: &v1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+									NodeSelectorTerms: []v1.NodeSelectorTerm{
+										{
+											MatchFields: []v1.NodeSelectorRequirement{
+												{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: p.nodes},
+											},
+										},
+									},
+								},
+							},
+						},
+						InitContainers: []v1.Container{
+							{Name: "prepull", Image: p.image, Command: []string{"sh", "-c", "true"}},
+						},
+						Containers: []v1.Container{
+							{Name: "pause", Image: o.pauseImage},
+						},
+						Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}},
+					},
+				},
+			},
+		})
+	}
+
+	return dss
+}
+
+// newPrepullJobs returns a Job for each node
+func (o *PrepullOptions) newPrepullJobs(plans []prepullPlan) []*batchv1.Job {
+
+	// node -> images
+	nodes := []string{}
+	images := map[string][]string{}
+	for _, p := range plans {
+		for _, n := range p.nodes {
+			if images[n] == nil {
+				nodes = append(nodes, n)
+			}
+			images[n] = append(images[n], p.image)
+		}
+	}
+
+	var backoffLimit int32
+	jobs := []*batchv1.Job{}
+	for _, n := range nodes {
+
+		containers := []v1.Container{}
+		for i, image := range images[n] {
+			containers = append(containers, v1.Container{
+				Name:    fmt.Sprintf("prepull-%d", i),
+				Image:   image,
+				Command: []string{"sh", "-c", "true"},
+			})
+		}
+
+		jobs = append(jobs, &batchv1.Job{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: metav1.ObjectMeta{Name: getPrepullName(n), Namespace: o.namespace, Labels: prepullLabels},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: &backoffLimit,
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: prepullLabels},
+					Spec: v1.PodSpec{
+						NodeName:      n,
+						RestartPolicy: v1.RestartPolicyNever,
+						Containers:    containers,
+						Tolerations:   []v1.Toleration{{Operator: v1.TolerationOpExists}},
+					},
+				},
+			},
+		})
+	}
+
+	return jobs
+}
+
+// createPrepullObjects creates DaemonSets or Jobs
+func (o *PrepullOptions) createPrepullObjects(objects []runtime.Object) error {
+
+	for _, obj := range objects {
+		switch r := obj.(type) {
+		case *appsv1.DaemonSet:
+			if _, err := o.daemonSetClient.Create(r); err != nil {
+				return fmt.Errorf("failed to create daemonset: %v", err)
+			}
+			fmt.Fprintf(o.Out, "daemonset.apps/%s created\n", r.ObjectMeta.Name)
+		case *batchv1.Job:
+			if _, err := o.jobClient.Create(r); err != nil {
+				return fmt.Errorf("failed to create job: %v", err)
+			}
+			fmt.Fprintf(o.Out, "job.batch/%s created\n", r.ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
+
+// getPrepullName returns resource name from image or node name
+// Long names are cut and suffixed with a hash of s to keep them unique.
+func getPrepullName(s string) string {
+
+	name := "dfi-prepull-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(name) <= 63 {
+		return name
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:10]
+	return strings.TrimRight(name[:63-len(hash)-1], "-") + "-" + hash
+}
+
+// getManifestImages returns images in pod specs of manifest
+func getManifestImages(b []byte) ([]string, error) {
+
+	images := []string{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for _, doc := range strings.Split(string(b), "\n---") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		i, err := getObjectImages(decoder, []byte(doc))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, i...)
+	}
+
+	return images, nil
+}
+
+// getObjectImages returns images in pod spec of an object in manifest
+// Items of List are expanded. Custom resources and objects without pod spec
+// are skipped.
+func getObjectImages(decoder runtime.Decoder, data []byte) ([]string, error) {
+
+	obj, _, err := decoder.Decode(data, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %v", err)
+	}
+
+	var spec v1.PodSpec
+	switch r := obj.(type) {
+	case *v1.List:
+		images := []string{}
+		for _, item := range r.Items {
+			i, err := getObjectImages(decoder, item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, i...)
+		}
+		return images, nil
+	case *v1.Pod:
+		spec = r.Spec
+	case *appsv1.Deployment:
+		spec = r.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		spec = r.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		spec = r.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		spec = r.Spec.Template.Spec
+	case *batchv1.Job:
+		spec = r.Spec.Template.Spec
+	case *batchv1beta1.CronJob:
+		spec = r.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil, nil
+	}
+
+	return util.GetPodImages(v1.Pod{Spec: spec}), nil
+}
@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+)
+
+// testManifest is manifest for prepull
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.31
+      containers:
+      - name: web
+        image: nginx:1.17
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`
+
+func TestNewPrepullOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &PrepullOptions{
+		DfiOptions: dfi,
+		images:     []string{},
+		filename:   "",
+		mode:       "daemonset",
+		pauseImage: "k8s.gcr.io/pause:3.1",
+		apply:      false,
+	}
+
+	actual := NewPrepullOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestPrepullValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		images      []string
+		filename    string
+		mode        string
+		expected    string
+	}{
+		{"image", []string{"nginx"}, "", "daemonset", ""},
+		{"filename", []string{}, "deploy.yaml", "job", ""},
+		{"no images", []string{}, "", "daemonset", "--image or --filename is required"},
+		{"invalid mode", []string{"nginx"}, "", "pod", "invalid mode: pod (valid values: daemonset, job)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &PrepullOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				images:     test.images,
+				filename:   test.filename,
+				mode:       test.mode,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestPrepullRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		mode        string
+		apply       bool
+		args        []string
+		expected    []string
+	}{
+		{"daemonset", "daemonset", false, []string{}, []string{"kind: DaemonSet", "name: dfi-prepull-image2", "- node2", "image: image2"}},
+		{"job", "job", false, []string{}, []string{"kind: Job", "name: dfi-prepull-node2", "nodeName: node2", "image: image2"}},
+		{"apply", "daemonset", true, []string{}, []string{"daemonset.apps/dfi-prepull-image2 created"}},
+		// size of image2 is taken from node1 which is not selected
+		{"node by name", "daemonset", false, []string{"node2"}, []string{"kind: DaemonSet", "- node2", "image: image2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+
+			buffer := &bytes.Buffer{}
+			errBuffer := &bytes.Buffer{}
+			o := &PrepullOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams:     genericclioptions.IOStreams{Out: buffer, ErrOut: errBuffer},
+					bytes:         true,
+					critThreshold: 50,
+					nodeClient:    fakeClient.CoreV1().Nodes(),
+				},
+				images:          []string{"image1", "image2"},
+				mode:            test.mode,
+				apply:           test.apply,
+				pauseImage:      "k8s.gcr.io/pause:3.1",
+				namespace:       "default",
+				daemonSetClient: fakeClient.AppsV1().DaemonSets("default"),
+				jobClient:       fakeClient.BatchV1().Jobs("default"),
+			}
+
+			if err := o.Run(test.args); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			for _, e := range test.expected {
+				if !strings.Contains(buffer.String(), e) {
+					t.Errorf("[%s] expected(%s) not found (got: %s)", test.description, e, buffer.String())
+				}
+			}
+
+			// image1 is on all nodes
+			if strings.Contains(buffer.String(), "image1") {
+				t.Errorf("[%s] image1 should not be pulled (got: %s)", test.description, buffer.String())
+			}
+
+			plan := strings.Join([]string{
+				"NAME    IMAGE SIZE   IMAGE NAME   STATUS",
+				"node2   1000B        image2       pull",
+				"",
+			}, "\n")
+			if errBuffer.String() != plan {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, plan, errBuffer.String())
+			}
+		})
+	}
+}
+
+func TestGetPrepullPlans(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		crit        int64
+		images      []string
+		expected    []prepullPlan
+	}{
+		{
+			"pull",
+			50,
+			[]string{"image2", "docker.io/library/image2:latest", "image3"},
+			[]prepullPlan{
+				{image: "image2", size: 1000, nodes: []string{"node2"}},
+				{image: "image3", size: 0, nodes: []string{"node1", "node2"}},
+			},
+		},
+		{
+			"no headroom",
+			0,
+			[]string{"image2"},
+			[]prepullPlan{
+				{image: "image2", size: 1000, noHeadroom: []string{"node2"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &PrepullOptions{DfiOptions: &DfiOptions{critThreshold: test.crit}}
+			actual := o.getPrepullPlans(test.images, testNodes, getKnownImageSizes(testNodes))
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPrepullPlansHeadroom(t *testing.T) {
+
+	// crit threshold is 5000 bytes and 1000 bytes are used
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: v1.NodeStatus{
+				Images: []v1.ContainerImage{{Names: []string{"base"}, SizeBytes: 1000}},
+				Capacity: v1.ResourceList{
+					v1.ResourceEphemeralStorage: *resource.NewQuantity(10000, resource.DecimalSI),
+				},
+			},
+		},
+	}
+	sizes := map[string]int64{
+		util.NormalizeImageName("image1"): 2000,
+		util.NormalizeImageName("image2"): 2000,
+		util.NormalizeImageName("image3"): 1000,
+	}
+
+	// image2 fits alone but not with image1 planned before
+	expected := []prepullPlan{
+		{image: "image1", size: 2000, nodes: []string{"node1"}},
+		{image: "image2", size: 2000, noHeadroom: []string{"node1"}},
+		{image: "image3", size: 1000, nodes: []string{"node1"}},
+	}
+
+	o := &PrepullOptions{DfiOptions: &DfiOptions{critThreshold: 50}}
+	actual := o.getPrepullPlans([]string{"image1", "image2", "image3"}, nodes, sizes)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestNewPrepullJobs(t *testing.T) {
+
+	o := &PrepullOptions{DfiOptions: &DfiOptions{}, namespace: "default"}
+	plans := []prepullPlan{
+		{image: "image2", nodes: []string{"node2"}},
+		{image: "image3", nodes: []string{"node1", "node2"}},
+	}
+
+	jobs := o.newPrepullJobs(plans)
+
+	expected := map[string][]string{
+		"node2": {"image2", "image3"},
+		"node1": {"image3"},
+	}
+
+	if len(jobs) != len(expected) {
+		t.Errorf("expected(%d) jobs differ (got: %d)", len(expected), len(jobs))
+		return
+	}
+
+	for _, j := range jobs {
+		spec := j.Spec.Template.Spec
+		images := []string{}
+		for _, c := range spec.Containers {
+			images = append(images, c.Image)
+		}
+		if !reflect.DeepEqual(images, expected[spec.NodeName]) {
+			t.Errorf("[%s] expected(%v) differ (got: %v)", spec.NodeName, expected[spec.NodeName], images)
+		}
+		if spec.RestartPolicy != v1.RestartPolicyNever {
+			t.Errorf("[%s] expected(%s) differ (got: %s)", spec.NodeName, v1.RestartPolicyNever, spec.RestartPolicy)
+		}
+	}
+}
+
+func TestGetPrepullName(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		name        string
+		expected    string
+	}{
+		{"image", "nginx:1.17", "dfi-prepull-nginx-1-17"},
+		{"registry", "gcr.io/Project/App@sha256:abc", "dfi-prepull-gcr-io-project-app-sha256-abc"},
+		{"node", "node1.example.com", "dfi-prepull-node1-example-com"},
+		{"too long", strings.Repeat("a", 50) + "-" + strings.Repeat("b", 10), "dfi-prepull-" + strings.Repeat("a", 40) + "-c5e352e328"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getPrepullName(test.name)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPrepullNameUnique(t *testing.T) {
+
+	image := "gcr.io/my-project/some-team/very-long-application-image-name"
+	a := getPrepullName(image + ":v1.2.3")
+	b := getPrepullName(image + ":v1.2.4")
+
+	if a == b {
+		t.Errorf("expected different names (got: %s)", a)
+	}
+	for _, name := range []string{a, b} {
+		if len(name) > 63 {
+			t.Errorf("expected name within 63 characters (got: %s)", name)
+		}
+	}
+}
+
+func TestGetManifestImages(t *testing.T) {
+
+	t.Run("manifest", func(t *testing.T) {
+		expected := []string{"busybox:1.31", "nginx:1.17"}
+		actual, err := getManifestImages([]byte(testManifest))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: redis
+  spec:
+    containers:
+    - name: redis
+      image: redis:5
+- apiVersion: v1
+  kind: List
+  items:
+  - apiVersion: batch/v1
+    kind: Job
+    metadata:
+      name: job
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: perl:5.30
+`
+		expected := []string{"redis:5", "perl:5.30"}
+		actual, err := getManifestImages([]byte(list))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		}
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		if _, err := getManifestImages([]byte("kind: Unknown")); err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// pullsLong defines long description
+	pullsLong = templates.LongDesc(`
+		Show image pull throughput calculated from kubelet "Pulled" events.
+
+		Pull time is taken from event message like
+		'Successfully pulled image "nginx" in 1.5s' and image size is taken
+		from the node. Events without pull time or image size are ignored.
+	`)
+
+	// pullsExample defines command examples
+	pullsExample = templates.Examples(`
+		# Show pull throughput per node.
+		kubectl dfi pulls
+
+		# Show pull throughput per registry.
+		kubectl dfi pulls --group-by registry
+
+		# Export pull throughput per image as csv.
+		kubectl dfi pulls --group-by image -o csv
+	`)
+
+	// pullsGroupKeys defines valid keys for --group-by
+	pullsGroupKeys = []string{"node", "registry", "image"}
+
+	// pulledMessage matches message of "Pulled" event
+	// Duration has several units for long pulls (e.g. "1m2.345s").
+	pulledMessage = regexp.MustCompile(`^Successfully pulled image "([^"]+)" in ((?:[0-9.]+[a-zµ]+)+)`)
+)
+
+// PullsOptions is struct of pulls options
+type PullsOptions struct {
+	*DfiOptions
+
+	// pulls options
+	groupBy string
+
+	// k8s event client
+	eventClient clientv1.EventInterface
+}
+
+// imagePull is a pull of image parsed from event
+type imagePull struct {
+	node     string
+	image    string
+	size     int64
+	duration time.Duration
+}
+
+// pullStats is statistics of pulls
+type pullStats struct {
+	key      string
+	pulls    int
+	bytes    int64
+	duration time.Duration
+}
+
+// throughput returns bytes per second
+func (s pullStats) throughput() int64 {
+	if s.duration <= 0 {
+		return 0
+	}
+	return int64(float64(s.bytes) / s.duration.Seconds())
+}
+
+// NewPullsOptions is an instance of PullsOptions
+func NewPullsOptions(dfi *DfiOptions) *PullsOptions {
+	return &PullsOptions{
+		DfiOptions: dfi,
+		groupBy:    "node",
+	}
+}
+
+// NewCmdPulls is a cobra command wrapping
+func NewCmdPulls(dfi *DfiOptions) *cobra.Command {
+	o := NewPullsOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "pulls",
+		Short:   "Show image pull throughput from kubelet events.",
+		Long:    pullsLong,
+		Example: pullsExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// string option
+	cmd.Flags().StringVarP(&o.groupBy, "group-by", "", o.groupBy, `Group pulls by one of `+strings.Join(pullsGroupKeys, "|")+`.`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *PullsOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	o.eventClient = o.clientset.CoreV1().Events(metav1.NamespaceAll)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *PullsOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if !containsString(pullsGroupKeys, o.groupBy) {
+		return fmt.Errorf("invalid group key: %s (valid keys: %s)", o.groupBy, strings.Join(pullsGroupKeys, ", "))
+	}
+
+	return nil
+}
+
+// Run printing pull throughput
+func (o *PullsOptions) Run(args []string) error {
+
+	// get events
+	el, elerr := o.eventClient.List(metav1.ListOptions{FieldSelector: "reason=Pulled"})
+	if elerr != nil {
+		return fmt.Errorf("failed to get events: %v", elerr)
+	}
+
+	// get nodes
+	nodes, err := o.getNodes([]string{})
+	if err != nil {
+		return err
+	}
+
+	stats := o.getPullStats(getImagePulls(el.Items, nodes))
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: strings.ToUpper(o.groupBy), Type: table.String},
+		{Name: "PULLS", Type: table.Count},
+		{Name: "PULLED", Type: table.Bytes},
+		{Name: "TIME", Type: table.Number},
+		{Name: "THROUGHPUT", Type: table.Bytes},
+	})
+
+	for _, s := range stats {
+		throughput := o.bytesCell(s.throughput())
+		throughput.Text += "/s"
+		row := []table.Cell{
+			{Text: s.key},
+			countCell(s.pulls),
+			o.bytesCell(s.bytes),
+			{
+				Text:  s.duration.Round(time.Millisecond).String(),
+				Value: int64(s.duration.Seconds()),
+				Raw:   strconv.FormatFloat(s.duration.Seconds(), 'f', 3, 64),
+			},
+			throughput,
+		}
+		o.table.AddCells(row)
+	}
+
+	return o.table.Print()
+}
+
+// getPullStats groups pulls by group key
+func (o *PullsOptions) getPullStats(pulls []imagePull) []pullStats {
+
+	groups := map[string]*pullStats{}
+	for _, p := range pulls {
+
+		var key string
+		switch o.groupBy {
+		case "registry":
+			key = util.GetImageRegistry(p.image)
+		case "image":
+			key = p.image
+		default:
+			key = p.node
+		}
+
+		if groups[key] == nil {
+			groups[key] = &pullStats{key: key}
+		}
+		groups[key].pulls++
+		groups[key].bytes += p.size
+		groups[key].duration += p.duration
+	}
+
+	stats := []pullStats{}
+	for _, s := range groups {
+		stats = append(stats, *s)
+	}
+
+	// slower first
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].throughput() != stats[j].throughput() {
+			return stats[i].throughput() < stats[j].throughput()
+		}
+		return stats[i].key < stats[j].key
+	})
+
+	return stats
+}
+
+// getImagePulls parses "Pulled" events and joins them with image size on nodes
+func getImagePulls(events []v1.Event, nodes []v1.Node) []imagePull {
+
+	images := map[string][]v1.ContainerImage{}
+	for _, node := range nodes {
+		images[node.ObjectMeta.Name] = node.Status.Images
+	}
+
+	pulls := []imagePull{}
+	for _, e := range events {
+		if e.Reason != "Pulled" {
+			continue
+		}
+
+		m := pulledMessage.FindStringSubmatch(e.Message)
+		if m == nil {
+			continue
+		}
+
+		d, err := time.ParseDuration(m[2])
+		if err != nil || d <= 0 {
+			continue
+		}
+
+		// node is out of selector or image has been removed
+		image, found := util.FindImage(images[e.Source.Host], m[1])
+		if !found {
+			continue
+		}
+
+		pulls = append(pulls, imagePull{
+			node:     e.Source.Host,
+			image:    m[1],
+			size:     image.SizeBytes,
+			duration: d,
+		})
+	}
+
+	return pulls
+}
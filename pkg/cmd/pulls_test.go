@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test event objects
+var testPullEvents = []v1.Event{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "e1", Namespace: "default"},
+		Reason:     "Pulled",
+		Message:    `Successfully pulled image "image2" in 1s`,
+		Source:     v1.EventSource{Component: "kubelet", Host: "node1"},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "e2", Namespace: "default"},
+		Reason:     "Pulled",
+		Message:    `Successfully pulled image "image1" in 500ms`,
+		Source:     v1.EventSource{Component: "kubelet", Host: "node2"},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "e3", Namespace: "default"},
+		Reason:     "Pulled",
+		Message:    `Successfully pulled image "image1"`,
+		Source:     v1.EventSource{Component: "kubelet", Host: "node2"},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "e4", Namespace: "default"},
+		Reason:     "Pulling",
+		Message:    `pulling image "image1"`,
+		Source:     v1.EventSource{Component: "kubelet", Host: "node2"},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "e5", Namespace: "default"},
+		Reason:     "Pulled",
+		Message:    `Successfully pulled image "image9" in 2s`,
+		Source:     v1.EventSource{Component: "kubelet", Host: "node1"},
+	},
+}
+
+func TestNewPullsOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &PullsOptions{
+		DfiOptions: dfi,
+		groupBy:    "node",
+	}
+
+	actual := NewPullsOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestPullsValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		groupBy     string
+		expected    string
+	}{
+		{"registry", "registry", ""},
+		{"invalid", "pod", "invalid group key: pod (valid keys: node, registry, image)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &PullsOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				groupBy:    test.groupBy,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestPullsRun(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		groupBy     string
+		output      string
+		expected    []string
+	}{
+		{
+			"group by node",
+			"node",
+			"",
+			[]string{
+				"NODE    PULLS   PULLED    TIME   THROUGHPUT",
+				"node1       1    1000B      1s      1000B/s",
+				"node2       1    2000B   500ms      4000B/s",
+				"",
+			},
+		},
+		{
+			"group by registry",
+			"registry",
+			"",
+			[]string{
+				"REGISTRY    PULLS   PULLED   TIME   THROUGHPUT",
+				"docker.io       2    3000B   1.5s      2000B/s",
+				"",
+			},
+		},
+		{
+			"csv",
+			"node",
+			"csv",
+			[]string{
+				"NODE,PULLS,PULLED,TIME,THROUGHPUT",
+				"node1,1,1000,1.000,1000",
+				"node2,1,2000,0.500,4000",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(
+				&testNodes[0], &testNodes[1],
+				&testPullEvents[0], &testPullEvents[1], &testPullEvents[2], &testPullEvents[3], &testPullEvents[4],
+			)
+
+			buffer := &bytes.Buffer{}
+			o := &PullsOptions{
+				DfiOptions: &DfiOptions{
+					bytes:      true,
+					nocolor:    true,
+					output:     test.output,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeClient.CoreV1().Nodes(),
+				},
+				groupBy:     test.groupBy,
+				eventClient: fakeClient.CoreV1().Events(metav1.NamespaceAll),
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestGetImagePulls(t *testing.T) {
+
+	expected := []imagePull{
+		{node: "node1", image: "image2", size: 1000, duration: time.Second},
+		{node: "node2", image: "image1", size: 2000, duration: 500 * time.Millisecond},
+	}
+
+	actual := getImagePulls(testPullEvents, testNodes)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestPulledMessage(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		message     string
+		expected    time.Duration
+	}{
+		{"milliseconds", `Successfully pulled image "image1" in 500ms`, 500 * time.Millisecond},
+		{"seconds", `Successfully pulled image "image1" in 2.5s`, 2500 * time.Millisecond},
+		{"minutes", `Successfully pulled image "image1" in 1m2.5s`, 62500 * time.Millisecond},
+		{"hours", `Successfully pulled image "image1" in 1h2m3s`, time.Hour + 2*time.Minute + 3*time.Second},
+		{"waiting", `Successfully pulled image "image1" in 1m2.5s (1m3s including waiting)`, 62500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m := pulledMessage.FindStringSubmatch(test.message)
+			if m == nil {
+				t.Errorf("[%s] message does not match: %s", test.description, test.message)
+				return
+			}
+			actual, err := time.ParseDuration(m[2])
+			if err != nil || actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s, %v)", test.description, test.expected, actual, err)
+			}
+		})
+	}
+}
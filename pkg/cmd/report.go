@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// reportLong defines long description
+	reportLong = templates.LongDesc(`
+		Generate a self-contained HTML report of image disk usage.
+
+		The report contains cluster totals, a sortable node table with usage
+		bars, images on each node and top images of the cluster. It has no
+		external assets, so it can be opened offline or attached to tickets.
+	`)
+
+	// reportExample defines command examples
+	reportExample = templates.Examples(`
+		# Write report to out.html.
+		kubectl dfi report --html out.html
+
+		# Write report of worker nodes with top 20 images to stdout.
+		kubectl dfi report --role worker --top 20 > out.html
+	`)
+)
+
+// ReportOptions is struct of report options
+type ReportOptions struct {
+	*DfiOptions
+
+	// report options
+	html string
+	top  int
+
+	// now returns generated time of report
+	now func() time.Time
+}
+
+// report is data of html report
+type report struct {
+	Generated string
+	Totals    reportTotals
+	Nodes     []reportNode
+	Top       int
+	TopImages []reportImage
+}
+
+// reportTotals is image usage of cluster
+type reportTotals struct {
+	Nodes    int
+	Images   int
+	Used     string
+	Capacity string
+	Percent  string
+}
+
+// reportNode is image usage of a node
+type reportNode struct {
+	Name        string
+	Used        table.Cell
+	Allocatable table.Cell
+	Capacity    table.Cell
+	Percent     table.Cell
+	Level       string
+	Images      []reportImage
+}
+
+// reportImage is an image on nodes
+type reportImage struct {
+	Name  string
+	Size  table.Cell
+	Nodes int
+	Total table.Cell
+}
+
+// NewReportOptions is an instance of ReportOptions
+func NewReportOptions(dfi *DfiOptions) *ReportOptions {
+	return &ReportOptions{
+		DfiOptions: dfi,
+		top:        10,
+		now:        time.Now,
+	}
+}
+
+// NewCmdReport is a cobra command wrapping
+func NewCmdReport(dfi *DfiOptions) *cobra.Command {
+	o := NewReportOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "report [NODE...]",
+		Short:   "Generate HTML report of image disk usage.",
+		Long:    reportLong,
+		Example: reportExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// int options
+	cmd.Flags().IntVarP(&o.top, "top", "", o.top, `Number of images in top images section.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.html, "html", "", o.html, `Write report to the file instead of stdout.`)
+
+	// image filter options
+	o.addImageFilterFlags(cmd.Flags())
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *ReportOptions) Prepare() error {
+	return o.DfiOptions.Prepare()
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *ReportOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.top < 0 {
+		return fmt.Errorf("--top must not be negative: %d", o.top)
+	}
+
+	return nil
+}
+
+// Run writing html report
+func (o *ReportOptions) Run(args []string) error {
+
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	r := o.getReport(nodes)
+
+	if o.html == "" {
+		return writeReport(o.Out, r)
+	}
+
+	f, err := os.Create(o.html)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %v", err)
+	}
+	defer f.Close()
+
+	if err := writeReport(f, r); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// getReport returns data of report
+func (o *ReportOptions) getReport(nodes []v1.Node) report {
+
+	r := report{
+		Generated: o.now().UTC().Format(time.RFC3339),
+		Nodes:     []reportNode{},
+		Top:       o.top,
+	}
+
+	var used, capacity int64
+	filtered := []v1.Node{}
+	for _, node := range nodes {
+		n := o.getReportNode(node)
+		r.Nodes = append(r.Nodes, n)
+
+		used += n.Used.Value
+		capacity += n.Capacity.Value
+
+		node.Status.Images = o.filterImages(node.Status.Images)
+		filtered = append(filtered, node)
+	}
+
+	images := o.getReportImages(filtered)
+	r.Totals = reportTotals{
+		Nodes:    len(nodes),
+		Images:   len(images),
+		Used:     o.toUnitOrZero(used),
+		Capacity: o.toUnit(capacity),
+		Percent:  o.reportPercent(used, capacity).Text,
+	}
+
+	if len(images) > o.top {
+		images = images[:o.top]
+	}
+	r.TopImages = images
+
+	return r
+}
+
+// getReportNode returns image usage of a node
+func (o *ReportOptions) getReportNode(node v1.Node) reportNode {
+
+	capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+	allocatable, _ := node.Status.Allocatable.StorageEphemeral().AsInt64()
+
+	images := o.filterImages(node.Status.Images)
+	used, _ := util.GetImageUsage(images)
+
+	n := reportNode{
+		Name:        node.ObjectMeta.Name,
+		Used:        o.bytesCell(used),
+		Allocatable: o.bytesCell(allocatable),
+		Capacity:    o.bytesCell(capacity),
+		Percent:     o.reportPercent(used, capacity),
+		Level:       getUsageLevel(used, capacity, o.warnThreshold, o.critThreshold),
+		Images:      []reportImage{},
+	}
+
+	sorted := append([]v1.ContainerImage{}, images...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SizeBytes > sorted[j].SizeBytes
+	})
+	for _, image := range sorted {
+		n.Images = append(n.Images, reportImage{
+			Name:  util.GetImageName(image),
+			Size:  o.bytesCell(image.SizeBytes),
+			Nodes: 1,
+			Total: o.bytesCell(image.SizeBytes),
+		})
+	}
+
+	return n
+}
+
+// getReportImages returns unique images on nodes with number of nodes
+// Images are sorted by total size on nodes in descending order.
+func (o *ReportOptions) getReportImages(nodes []v1.Node) []reportImage {
+
+	images := []v1.ContainerImage{}
+	counts := []int{}
+	index := imageIndex{}
+	for _, node := range nodes {
+		for _, image := range node.Status.Images {
+			i := index.find(image)
+			if i < 0 {
+				index.add(image, len(images))
+				images = append(images, image)
+				counts = append(counts, 1)
+				continue
+			}
+			if image.SizeBytes > images[i].SizeBytes {
+				images[i].SizeBytes = image.SizeBytes
+			}
+			counts[i]++
+		}
+	}
+
+	ret := []reportImage{}
+	for i, image := range images {
+		ret = append(ret, reportImage{
+			Name:  util.GetImageName(image),
+			Size:  o.bytesCell(image.SizeBytes),
+			Nodes: counts[i],
+			Total: o.bytesCell(image.SizeBytes * int64(counts[i])),
+		})
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Total.Value > ret[j].Total.Value
+	})
+
+	return ret
+}
+
+// reportPercent returns percentage without color
+func (o *ReportOptions) reportPercent(used, capacity int64) table.Cell {
+
+	if capacity == 0 {
+		return table.Cell{Text: "N/A"}
+	}
+
+	p := getPercent(used, capacity)
+	return table.Cell{Text: strconv.FormatInt(p, 10) + "%", Value: p, Raw: strconv.FormatInt(p, 10)}
+}
+
+// writeReport writes html report
+func writeReport(w io.Writer, r report) error {
+
+	if err := reportTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+
+	return nil
+}
+
+// reportTemplate is html template of report
+// Styles and scripts are inlined to make the report self-contained.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>kubectl dfi report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+th.sortable { cursor: pointer; user-select: none; }
+th.sortable:after { content: " \2195"; color: #aaa; }
+.num { text-align: right; }
+.bar { display: inline-block; width: 160px; height: 12px; background: #eee; vertical-align: middle; }
+.bar div { height: 100%; }
+.ok { background: #4caf50; }
+.warn { background: #ffc107; }
+.crit { background: #f44336; }
+.unknown { background: #9e9e9e; }
+details table { margin: 4px 0 0 0; font-size: 90%; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>kubectl dfi report</h1>
+<p>Generated at {{.Generated}}</p>
+
+<h2>Cluster Totals</h2>
+<table>
+<tr><th>Nodes</th><td class="num">{{.Totals.Nodes}}</td></tr>
+<tr><th>Images</th><td class="num">{{.Totals.Images}}</td></tr>
+<tr><th>Image Used</th><td class="num">{{.Totals.Used}}</td></tr>
+<tr><th>Capacity</th><td class="num">{{.Totals.Capacity}}</td></tr>
+<tr><th>%Used</th><td class="num">{{.Totals.Percent}}</td></tr>
+</table>
+
+<h2>Nodes</h2>
+<table class="sortable">
+<thead>
+<tr><th class="sortable">NAME</th><th class="sortable num">IMAGE USED</th><th class="sortable num">ALLOCATABLE</th><th class="sortable num">CAPACITY</th><th class="sortable">%USED</th></tr>
+</thead>
+<tbody>
+{{- range .Nodes}}
+<tr>
+<td data-sort="{{.Name}}"><details><summary>{{.Name}} ({{len .Images}} images)</summary>
+<table>
+{{- range .Images}}
+<tr><td class="num">{{.Size.Text}}</td><td>{{.Name}}</td></tr>
+{{- end}}
+</table>
+</details></td>
+<td class="num" data-sort="{{.Used.Value}}">{{.Used.Text}}</td>
+<td class="num" data-sort="{{.Allocatable.Value}}">{{.Allocatable.Text}}</td>
+<td class="num" data-sort="{{.Capacity.Value}}">{{.Capacity.Text}}</td>
+<td data-sort="{{.Percent.Value}}"><div class="bar"><div class="{{.Level}}" style="width: {{.Percent.Value}}%"></div></div> {{.Percent.Text}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Top {{.Top}} Images</h2>
+<table class="sortable">
+<thead>
+<tr><th class="sortable num">TOTAL</th><th class="sortable num">IMAGE SIZE</th><th class="sortable num">NODES</th><th class="sortable">IMAGE NAME</th></tr>
+</thead>
+<tbody>
+{{- range .TopImages}}
+<tr><td class="num" data-sort="{{.Total.Value}}">{{.Total.Text}}</td><td class="num" data-sort="{{.Size.Value}}">{{.Size.Text}}</td><td class="num" data-sort="{{.Nodes}}">{{.Nodes}}</td><td data-sort="{{.Name}}">{{.Name}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (t) {
+  t.querySelectorAll("thead th").forEach(function (th, i) {
+    th.addEventListener("click", function () {
+      var asc = th.getAttribute("data-order") !== "asc";
+      th.setAttribute("data-order", asc ? "asc" : "desc");
+      var rows = Array.prototype.slice.call(t.tBodies[0].rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[i].getAttribute("data-sort");
+        var y = b.cells[i].getAttribute("data-sort");
+        var d = isNaN(x) || isNaN(y) ? x.localeCompare(y) : x - y;
+        return asc ? d : -d;
+      });
+      rows.forEach(function (r) { t.tBodies[0].appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
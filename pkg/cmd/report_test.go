@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// testReportNow returns fixed time of report
+func testReportNow() time.Time {
+	return time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNewReportOptions(t *testing.T) {
+
+	dfi := &DfiOptions{}
+	actual := NewReportOptions(dfi)
+
+	if actual.DfiOptions != dfi || actual.top != 10 || actual.html != "" || actual.now == nil {
+		t.Errorf("unexpected options: %#v", actual)
+	}
+}
+
+func TestReportValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		top         int
+		expected    string
+	}{
+		{"top", 10, ""},
+		{"negative top", -1, "--top must not be negative: -1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &ReportOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				top:        test.top,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetReport(t *testing.T) {
+
+	o := &ReportOptions{
+		DfiOptions: &DfiOptions{bytes: true, warnThreshold: 25, critThreshold: 50},
+		top:        2,
+		now:        testReportNow,
+	}
+
+	r := o.getReport([]v1.Node{testDescribeNode, testNodes[0]})
+
+	if r.Generated != "2019-06-01T00:00:00Z" {
+		t.Errorf("expected(2019-06-01T00:00:00Z) differ (got: %s)", r.Generated)
+	}
+
+	expectedTotals := reportTotals{Nodes: 2, Images: 4, Used: "4500B", Capacity: "10000010000B", Percent: "0%"}
+	if !reflect.DeepEqual(r.Totals, expectedTotals) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expectedTotals, r.Totals)
+	}
+
+	// usage bars are colored by thresholds
+	levels := []string{}
+	for _, n := range r.Nodes {
+		levels = append(levels, n.Name+":"+n.Percent.Text+":"+n.Level)
+	}
+	expectedLevels := []string{"describe1:35%:warn", "node1:0%:ok"}
+	if !reflect.DeepEqual(levels, expectedLevels) {
+		t.Errorf("expected(%v) differ (got: %v)", expectedLevels, levels)
+	}
+
+	// images on node are sorted by size
+	images := []string{}
+	for _, i := range r.Nodes[0].Images {
+		images = append(images, i.Name)
+	}
+	expectedImages := []string{"gcr.io/project/app:v1.2.3", "docker.io/library/nginx:1.17.0", "gcr.io/project/sidecar:v1"}
+	if !reflect.DeepEqual(images, expectedImages) {
+		t.Errorf("expected(%v) differ (got: %v)", expectedImages, images)
+	}
+
+	top := []string{}
+	for _, i := range r.TopImages {
+		top = append(top, i.Name+":"+i.Total.Text)
+	}
+	expectedTop := []string{"gcr.io/project/app:v1.2.3:2000B", "docker.io/library/nginx:1.17.0:1000B"}
+	if !reflect.DeepEqual(top, expectedTop) {
+		t.Errorf("expected(%v) differ (got: %v)", expectedTop, top)
+	}
+}
+
+func TestGetReportImages(t *testing.T) {
+
+	o := &ReportOptions{DfiOptions: &DfiOptions{bytes: true}}
+
+	// image1 is on both nodes
+	actual := o.getReportImages(testNodes)
+
+	if len(actual) != 1 {
+		t.Errorf("expected 1 image (got: %#v)", actual)
+		return
+	}
+	if actual[0].Nodes != 2 || actual[0].Size.Value != 2000 || actual[0].Total.Value != 4000 {
+		t.Errorf("unexpected image: %#v", actual[0])
+	}
+}
+
+func TestReportRun(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		description string
+		html        string
+	}{
+		{"stdout", ""},
+		{"file", filepath.Join(dir, "out.html")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(&testDescribeNode)
+
+			buffer := &bytes.Buffer{}
+			o := &ReportOptions{
+				DfiOptions: &DfiOptions{
+					IOStreams:     genericclioptions.IOStreams{Out: buffer},
+					bytes:         true,
+					warnThreshold: 25,
+					critThreshold: 50,
+					nodeClient:    fakeClient.CoreV1().Nodes(),
+				},
+				html: test.html,
+				top:  10,
+				now:  testReportNow,
+			}
+
+			if err := o.Run([]string{}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := buffer.String()
+			if test.html != "" {
+				b, err := ioutil.ReadFile(test.html)
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+					return
+				}
+				actual = string(b)
+			}
+
+			for _, expected := range []string{
+				"<p>Generated at 2019-06-01T00:00:00Z</p>",
+				`<td data-sort="describe1"><details><summary>describe1 (3 images)</summary>`,
+				`<td data-sort="35"><div class="bar"><div class="warn" style="width: 35%"></div></div> 35%</td>`,
+				`<tr><td class="num" data-sort="2000">2000B</td><td class="num" data-sort="2000">2000B</td><td class="num" data-sort="1">1</td><td data-sort="gcr.io/project/app:v1.2.3">gcr.io/project/app:v1.2.3</td></tr>`,
+			} {
+				if !strings.Contains(actual, expected) {
+					t.Errorf("[%s] expected(%s) is not in report (got: %s)", test.description, expected, actual)
+				}
+			}
+
+			// no external assets
+			for _, external := range []string{"<link", "src="} {
+				if strings.Contains(actual, external) {
+					t.Errorf("[%s] unexpected external asset: %s", test.description, external)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/history"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// trendLong defines long description
+	trendLong = templates.LongDesc(`
+		Show growth of image disk usage recorded by "kubectl dfi --record".
+
+		GROWTH is slope of linear regression of IMAGE USED per day. CRIT IN
+		and FULL IN are forecasts of time until image usage crosses the
+		critical threshold of capacity and fills allocatable ephemeral storage.
+		Nodes are summed up by run with --group-by. Runs of a group may have
+		different numbers of nodes (e.g. scale up), so the line is fitted to
+		usage per node and GROWTH is the growth per node times nodes of the
+		last run.
+	`)
+
+	// trendExample defines command examples
+	trendExample = templates.Examples(`
+		# Record usage periodically (e.g. by cron).
+		kubectl dfi --record
+
+		# Show growth and forecast of nodes in last 7 days.
+		kubectl dfi trend
+
+		# Show growth of node pools in last 30 days.
+		kubectl dfi trend --group-by cloud.google.com/gke-nodepool --window 720h
+	`)
+
+	// sparks are levels of sparkline
+	sparks = []rune("▁▂▃▄▅▆▇█")
+)
+
+// forecastLimitDays is the longest forecast in days
+// Longer forecasts of tiny growth are meaningless and overflow time.Duration.
+const forecastLimitDays = 10 * 365
+
+// TrendOptions is struct of trend options
+type TrendOptions struct {
+	*DfiOptions
+
+	// trend options
+	groupBy string
+	window  time.Duration
+
+	// now returns current time to select records in window
+	now func() time.Time
+}
+
+// trendSample is usage of a node or a group at a time
+type trendSample struct {
+	time        time.Time
+	nodes       int
+	used        int64
+	allocatable int64
+	capacity    int64
+}
+
+// usedPerNode returns average used bytes of nodes in the sample
+func (s trendSample) usedPerNode() float64 {
+
+	if s.nodes <= 1 {
+		return float64(s.used)
+	}
+	return float64(s.used) / float64(s.nodes)
+}
+
+// trendSeries is samples of a node or a group in time order
+type trendSeries struct {
+	name    string
+	samples []trendSample
+}
+
+// NewTrendOptions is an instance of TrendOptions
+func NewTrendOptions(dfi *DfiOptions) *TrendOptions {
+	return &TrendOptions{
+		DfiOptions: dfi,
+		groupBy:    "",
+		window:     7 * 24 * time.Hour,
+		now:        time.Now,
+	}
+}
+
+// NewCmdTrend is a cobra command wrapping
+func NewCmdTrend(dfi *DfiOptions) *cobra.Command {
+	o := NewTrendOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "trend [NODE...]",
+		Short:   "Show growth and forecast of image disk usage from history.",
+		Long:    trendLong,
+		Example: trendExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// string option
+	cmd.Flags().StringVarP(&o.groupBy, "group-by", "", o.groupBy, `Sum up nodes by value of the label (e.g. node pool).`)
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	// duration option
+	cmd.Flags().DurationVarP(&o.window, "window", "", o.window, `Use records in the time window. 0 uses all records.`)
+
+	return cmd
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *TrendOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.validateTableOutput(); err != nil {
+		return err
+	}
+
+	if o.window < 0 {
+		return fmt.Errorf("--window must not be negative: %s", o.window)
+	}
+
+	return nil
+}
+
+// Run printing growth and forecast of image usage
+func (o *TrendOptions) Run(args []string) error {
+
+	records, err := history.Load(o.historyFile)
+	if err != nil {
+		return err
+	}
+
+	// filter by args and window
+	since := time.Time{}
+	if o.window > 0 {
+		since = o.now().Add(-o.window)
+	}
+	selected := []history.Record{}
+	for _, r := range records {
+		if len(args) > 0 && !containsString(args, r.Node) {
+			continue
+		}
+		if r.Time.Before(since) {
+			continue
+		}
+		selected = append(selected, r)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no history in %s (record usage with \"kubectl dfi --record\")", o.historyFile)
+	}
+
+	name := "NAME"
+	if o.groupBy != "" {
+		name = "GROUP"
+	}
+
+	// set printer header
+	o.setTableFormat()
+	o.table.AddColumns([]table.Column{
+		{Name: name, Type: table.String},
+		{Name: "SAMPLES", Type: table.Count},
+		{Name: "IMAGE USED", Type: table.Bytes},
+		{Name: "GROWTH", Type: table.Bytes},
+		{Name: "TREND", Type: table.String},
+		{Name: "CRIT IN", Type: table.String},
+		{Name: "FULL IN", Type: table.String},
+	})
+
+	for _, s := range getTrendSeries(selected, o.groupBy) {
+
+		last := s.samples[len(s.samples)-1]
+		slope, ok := getLinearSlope(s.samples)
+		if last.nodes > 1 {
+			slope *= float64(last.nodes)
+		}
+
+		growth := table.Cell{Text: "N/A"}
+		if ok {
+			growth = o.growthCell(int64(math.Round(slope * 24 * 60 * 60)))
+		}
+
+		o.table.AddCells([]table.Cell{
+			{Text: s.name},
+			countCell(len(s.samples)),
+			o.bytesCell(last.used),
+			growth,
+			{Text: getSparkline(s.samples, barWidth)},
+			{Text: getForecast(last.used, last.capacity*o.critThreshold/100, slope, ok)},
+			{Text: getForecast(last.used, last.allocatable, slope, ok)},
+		})
+	}
+
+	return o.table.Print()
+}
+
+// growthCell returns cell of growth per day with sign
+func (o *DfiOptions) growthCell(perDay int64) table.Cell {
+
+	sign := "+"
+	abs := perDay
+	if perDay < 0 {
+		sign = "-"
+		abs = -perDay
+	}
+	if perDay == 0 {
+		sign = ""
+	}
+
+	return table.Cell{Text: sign + o.toUnitOrZero(abs) + "/d", Value: perDay, Raw: strconv.FormatInt(perDay, 10)}
+}
+
+// getTrendSeries returns samples of nodes or groups
+// Nodes in a group are summed up by time of record (a run of --record).
+func getTrendSeries(records []history.Record, groupBy string) []trendSeries {
+
+	// time is keyed by nanoseconds because location of time.Time differs by parse
+	series := map[string]map[int64]*trendSample{}
+	for _, r := range records {
+		name := r.Node
+		if groupBy != "" {
+			name = r.Labels[groupBy]
+			if name == "" {
+				name = "<none>"
+			}
+		}
+
+		if series[name] == nil {
+			series[name] = map[int64]*trendSample{}
+		}
+		s := series[name][r.Time.UnixNano()]
+		if s == nil {
+			s = &trendSample{time: r.Time}
+			series[name][r.Time.UnixNano()] = s
+		}
+		s.nodes++
+		s.used += r.Used
+		s.allocatable += r.Allocatable
+		s.capacity += r.Capacity
+	}
+
+	ret := []trendSeries{}
+	for name, samples := range series {
+		t := trendSeries{name: name}
+		for _, s := range samples {
+			t.samples = append(t.samples, *s)
+		}
+		sort.Slice(t.samples, func(i, j int) bool {
+			return t.samples[i].time.Before(t.samples[j].time)
+		})
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].name < ret[j].name
+	})
+
+	return ret
+}
+
+// getLinearSlope returns slope of used bytes per node per second by least squares
+// It returns false if samples are not enough to fit a line.
+func getLinearSlope(samples []trendSample) (float64, bool) {
+
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	var mx, my float64
+	for _, s := range samples {
+		mx += s.time.Sub(samples[0].time).Seconds()
+		my += s.usedPerNode()
+	}
+	mx /= float64(len(samples))
+	my /= float64(len(samples))
+
+	var sxy, sxx float64
+	for _, s := range samples {
+		dx := s.time.Sub(samples[0].time).Seconds() - mx
+		sxy += dx * (s.usedPerNode() - my)
+		sxx += dx * dx
+	}
+	if sxx == 0 {
+		return 0, false
+	}
+
+	return sxy / sxx, true
+}
+
+// getForecast returns time until used reaches target with slope
+func getForecast(used, target int64, slope float64, ok bool) string {
+
+	if target <= 0 {
+		return "N/A"
+	}
+	if used >= target {
+		return "now"
+	}
+	if !ok {
+		return "N/A"
+	}
+	if slope <= 0 {
+		return "never"
+	}
+
+	// clamp in seconds before converting to time.Duration
+	seconds := float64(target-used) / slope
+	if seconds > forecastLimitDays*24*60*60 {
+		return fmt.Sprintf(">%dd", forecastLimitDays)
+	}
+
+	d := time.Duration(math.Round(seconds)) * time.Second
+	switch {
+	case d < time.Hour:
+		return "<1h"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int64(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int64(d.Hours()/24))
+	}
+}
+
+// getSparkline returns sparkline of used bytes per node in last samples
+func getSparkline(samples []trendSample, width int) string {
+
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+
+	min, max := samples[0].usedPerNode(), samples[0].usedPerNode()
+	for _, s := range samples {
+		if u := s.usedPerNode(); u < min {
+			min = u
+		}
+		if u := s.usedPerNode(); u > max {
+			max = u
+		}
+	}
+
+	line := []rune{}
+	for _, s := range samples {
+		level := 0
+		if max > min {
+			level = int((s.usedPerNode() - min) * float64(len(sparks)-1) / (max - min))
+		}
+		line = append(line, sparks[level])
+	}
+
+	return string(line)
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/history"
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// testTrendDay returns time of n-th day of test records
+func testTrendDay(n int) time.Time {
+	return time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+// test records of history
+// node1 grows 1000 bytes per day and node2 does not grow.
+var testTrendRecords = []history.Record{
+	{Time: testTrendDay(-10), Node: "node3", Used: 4000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(0), Node: "node1", Labels: map[string]string{"pool": "a"}, Used: 1000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(0), Node: "node2", Labels: map[string]string{"pool": "a"}, Used: 2000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(1), Node: "node1", Labels: map[string]string{"pool": "a"}, Used: 2000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(1), Node: "node2", Labels: map[string]string{"pool": "a"}, Used: 2000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(2), Node: "node1", Labels: map[string]string{"pool": "a"}, Used: 3000, Allocatable: 8000, Capacity: 10000},
+	{Time: testTrendDay(2), Node: "node2", Labels: map[string]string{"pool": "a"}, Used: 2000, Allocatable: 8000, Capacity: 10000},
+}
+
+func TestNewTrendOptions(t *testing.T) {
+
+	dfi := &DfiOptions{}
+	actual := NewTrendOptions(dfi)
+
+	if actual.DfiOptions != dfi || actual.groupBy != "" || actual.window != 7*24*time.Hour || actual.now == nil {
+		t.Errorf("unexpected options: %#v", actual)
+	}
+}
+
+func TestTrendValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		window      time.Duration
+		output      string
+		expected    string
+	}{
+		{"window", time.Hour, "", ""},
+		{"all records", 0, "csv", ""},
+		{"negative window", -time.Hour, "", "--window must not be negative: -1h0m0s"},
+		{"wide output", time.Hour, "wide", "invalid output: wide (valid values: csv, tsv, markdown)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &TrendOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50, output: test.output},
+				window:     test.window,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestTrendRun(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history.jsonl")
+	if err := history.Append(path, testTrendRecords); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	var tests = []struct {
+		description string
+		args        []string
+		groupBy     string
+		window      time.Duration
+		output      string
+		expected    []string
+	}{
+		{
+			"nodes",
+			[]string{},
+			"",
+			7 * 24 * time.Hour,
+			"",
+			[]string{
+				"NAME    SAMPLES   IMAGE USED     GROWTH   TREND   CRIT IN   FULL IN",
+				"node1         3        3000B   +1000B/d   ▁▄█     2d        5d",
+				"node2         3        2000B       0B/d   ▁▁▁     never     never",
+				"",
+			},
+		},
+		{
+			"group by pool",
+			[]string{},
+			"pool",
+			7 * 24 * time.Hour,
+			"",
+			[]string{
+				"GROUP   SAMPLES   IMAGE USED     GROWTH   TREND   CRIT IN   FULL IN",
+				"a             3        5000B   +1000B/d   ▁▄█     5d        11d",
+				"",
+			},
+		},
+		{
+			"single sample",
+			[]string{"node3"},
+			"",
+			0,
+			"",
+			[]string{
+				"NAME    SAMPLES   IMAGE USED   GROWTH   TREND   CRIT IN   FULL IN",
+				"node3         1        4000B      N/A   ▁       N/A       N/A",
+				"",
+			},
+		},
+		{
+			"csv",
+			[]string{},
+			"",
+			7 * 24 * time.Hour,
+			"csv",
+			[]string{
+				"NAME,SAMPLES,IMAGE USED,GROWTH,TREND,CRIT IN,FULL IN",
+				"node1,3,3000,1000,▁▄█,2d,5d",
+				"node2,3,2000,0,▁▁▁,never,never",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &TrendOptions{
+				DfiOptions: &DfiOptions{
+					bytes:         true,
+					nocolor:       true,
+					output:        test.output,
+					historyFile:   path,
+					critThreshold: 50,
+					table:         table.NewOutputTable(buffer),
+				},
+				groupBy: test.groupBy,
+				window:  test.window,
+				now:     func() time.Time { return testTrendDay(2) },
+			}
+
+			if err := o.Run(test.args); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+
+	// no records in window
+	o := &TrendOptions{
+		DfiOptions: &DfiOptions{historyFile: filepath.Join(dir, "missing.jsonl")},
+		now:        time.Now,
+	}
+	expected := "no history in " + o.historyFile + " (record usage with \"kubectl dfi --record\")"
+	if err := o.Run([]string{}); err == nil || err.Error() != expected {
+		t.Errorf("expected(%s) differ (got: %v)", expected, err)
+	}
+}
+
+func TestGetForecast(t *testing.T) {
+
+	perDay := 1000.0 / (24 * 60 * 60)
+
+	var tests = []struct {
+		description string
+		used        int64
+		target      int64
+		slope       float64
+		ok          bool
+		expected    string
+	}{
+		{"days", 1000, 5000, perDay, true, "4d"},
+		{"hours", 1000, 1500, perDay, true, "12h"},
+		{"soon", 1000, 1010, perDay, true, "<1h"},
+		{"reached", 5000, 5000, perDay, true, "now"},
+		{"shrinking", 1000, 5000, -perDay, true, "never"},
+		{"before limit", 1000, 1000 + 1000*(forecastLimitDays-1), perDay, true, "3649d"},
+		{"over limit", 1000, 1000 + 1000*(forecastLimitDays+1), perDay, true, ">3650d"},
+		{"tiny growth", 0, 50 * 1000 * 1000 * 1000, 100.0 / (24 * 60 * 60), true, ">3650d"},
+		{"not enough samples", 1000, 5000, 0, false, "N/A"},
+		{"unknown target", 1000, 0, perDay, true, "N/A"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getForecast(test.used, test.target, test.slope, test.ok)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetSparkline(t *testing.T) {
+
+	samples := []trendSample{}
+	for _, used := range []int64{5000, 1000, 2000, 8000} {
+		samples = append(samples, trendSample{used: used})
+	}
+
+	var tests = []struct {
+		description string
+		samples     []trendSample
+		width       int
+		expected    string
+	}{
+		{"all samples", samples, 10, "▅▁▂█"},
+		{"last samples", samples, 2, "▁█"},
+		{"flat", samples[:1], 10, "▁"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getSparkline(test.samples, test.width)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetLinearSlope(t *testing.T) {
+
+	samples := []trendSample{
+		{time: testTrendDay(0), used: 1000},
+		{time: testTrendDay(1), used: 3000},
+		{time: testTrendDay(2), used: 3000},
+	}
+
+	// (1000, 3000, 3000) fits to 1000 per day
+	slope, ok := getLinearSlope(samples)
+	if !ok || int64(slope*24*60*60+0.5) != 1000 {
+		t.Errorf("unexpected slope: %f, %v", slope, ok)
+	}
+
+	if _, ok := getLinearSlope(samples[:1]); ok {
+		t.Errorf("slope of a sample should not be fitted")
+	}
+
+	if !reflect.DeepEqual(getTrendSeries(nil, ""), []trendSeries{}) {
+		t.Errorf("series of no records should be empty")
+	}
+}
+
+func TestGetLinearSlopeScaleUp(t *testing.T) {
+
+	// a node is added to the group, but usage of nodes does not grow
+	records := []history.Record{
+		{Time: testTrendDay(0), Node: "node1", Used: 1000},
+		{Time: testTrendDay(1), Node: "node1", Used: 1000},
+		{Time: testTrendDay(1), Node: "node2", Used: 1000},
+		{Time: testTrendDay(2), Node: "node1", Used: 1000},
+		{Time: testTrendDay(2), Node: "node2", Used: 1000},
+	}
+
+	series := getTrendSeries(records, "pool")
+	if len(series) != 1 || len(series[0].samples) != 3 || series[0].samples[2].nodes != 2 {
+		t.Errorf("unexpected series: %#v", series)
+		return
+	}
+
+	slope, ok := getLinearSlope(series[0].samples)
+	if !ok || slope != 0 {
+		t.Errorf("unexpected slope: %f, %v", slope, ok)
+	}
+}
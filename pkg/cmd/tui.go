@@ -0,0 +1,760 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/term"
+)
+
+var (
+	// tuiLong defines long description
+	tuiLong = templates.LongDesc(`
+		Explore image disk usage interactively in a full-screen terminal UI.
+
+		Nodes are sorted by usage. Press Enter on a node to show its images,
+		and press Enter on an image to show nodes which have it and pods
+		using it. Data is refreshed in background, and keys are handled
+		while loading.
+
+		Keys:
+		  Up/Down, k/j   move cursor
+		  Enter          open selected row
+		  Esc, Left, h   go back
+		  /              filter rows (Enter to apply, Esc to clear)
+		  r              refresh now
+		  q, Ctrl-C      quit
+	`)
+
+	// tuiExample defines command examples
+	tuiExample = templates.Examples(`
+		# Explore image usage of all nodes.
+		kubectl dfi tui
+
+		# Explore worker nodes and refresh every 10 seconds.
+		kubectl dfi tui --role worker --refresh 10s
+	`)
+)
+
+// escape sequences of terminal
+const (
+	tuiAltScreen  = "\x1b[?1049h\x1b[?25l"
+	tuiMainScreen = "\x1b[?25h\x1b[?1049l"
+	tuiClear      = "\x1b[H\x1b[2J"
+	tuiReverse    = "\x1b[7m"
+	tuiReset      = "\x1b[0m"
+)
+
+// tuiView is a screen of tui
+type tuiView int
+
+const (
+	// tuiNodes lists nodes
+	tuiNodes tuiView = iota
+	// tuiImages lists images on a node
+	tuiImages
+	// tuiImage shows nodes and pods of an image
+	tuiImage
+)
+
+// TuiOptions is struct of tui options
+type TuiOptions struct {
+	*DfiOptions
+
+	// tui options
+	refresh time.Duration
+
+	// k8s pod client
+	podClient clientv1.PodInterface
+}
+
+// tuiNode is a node with filtered images
+type tuiNode struct {
+	name     string
+	used     int64
+	capacity int64
+	images   []v1.ContainerImage
+}
+
+// tuiRow is a row of tui
+// key is opened by Enter, and text is matched with filter.
+type tuiRow struct {
+	cells []table.Cell
+	key   string
+	text  string
+}
+
+// tuiResult is result of loading nodes and pods
+// Nodes and pods are not loaded if fetching fails, and err is set.
+type tuiResult struct {
+	nodes  []v1.Node
+	pods   []v1.Pod
+	loaded bool
+	err    error
+}
+
+// tuiState is state of a screen
+// States of previous screens are kept to go back.
+type tuiState struct {
+	view   tuiView
+	node   string
+	image  v1.ContainerImage
+	cursor int
+	filter string
+}
+
+// tuiModel is state of tui
+type tuiModel struct {
+	tuiState
+
+	o *DfiOptions
+
+	// data
+	nodes []tuiNode
+	pods  []v1.Pod
+	err   error
+
+	// indexes of nodes by normalized image name
+	// Nodes of an image are shown in every image row, so they are not searched each time.
+	imageNodeIndex map[string][]int
+
+	// screen
+	history   []tuiState
+	filtering bool
+
+	// requests to run loop
+	reload bool
+	quit   bool
+
+	// loading is true while data is loaded in background
+	loading bool
+}
+
+// NewTuiOptions is an instance of TuiOptions
+func NewTuiOptions(dfi *DfiOptions) *TuiOptions {
+	return &TuiOptions{
+		DfiOptions: dfi,
+		refresh:    30 * time.Second,
+	}
+}
+
+// NewCmdTui is a cobra command wrapping
+func NewCmdTui(dfi *DfiOptions) *cobra.Command {
+	o := NewTuiOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "tui [NODE...]",
+		Short:   "Explore image disk usage in terminal UI.",
+		Long:    tuiLong,
+		Example: tuiExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// duration option
+	cmd.Flags().DurationVarP(&o.refresh, "refresh", "", o.refresh, `Interval to refresh data. 0 disables periodic refresh.`)
+
+	// image filter options
+	o.addImageFilterFlags(cmd.Flags())
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *TuiOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	o.podClient = o.clientset.CoreV1().Pods(metav1.NamespaceAll)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *TuiOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.refresh < 0 {
+		return fmt.Errorf("--refresh must not be negative: %s", o.refresh)
+	}
+
+	return nil
+}
+
+// Run starting terminal ui
+func (o *TuiOptions) Run(args []string) error {
+
+	in, ok := o.In.(*os.File)
+	if !ok || !term.IsTerminal(in) {
+		return fmt.Errorf("tui requires a terminal")
+	}
+
+	m := &tuiModel{o: o.DfiOptions}
+	r := o.load(args)
+	if r.err != nil {
+		return r.err
+	}
+	m.setResult(r)
+
+	tty := term.TTY{In: o.In, Out: o.Out, Raw: true}
+	return tty.Safe(func() error {
+		fmt.Fprint(o.Out, tuiAltScreen)
+		defer fmt.Fprint(o.Out, tuiMainScreen)
+
+		return o.loop(m, args)
+	})
+}
+
+// loop draws screen until quit
+// Keys are read and data is loaded in background because they block. Only
+// one load runs at a time, and model is updated only by loop.
+func (o *TuiOptions) loop(m *tuiModel, args []string) error {
+
+	keys := make(chan string)
+	go readTuiKeys(o.In, keys)
+
+	// buffered not to block load which finishes after quit
+	results := make(chan tuiResult, 1)
+
+	var tick <-chan time.Time
+	if o.refresh > 0 {
+		ticker := time.NewTicker(o.refresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		width, height := o.getTerminalSize()
+		fmt.Fprint(o.Out, tuiClear+strings.Join(m.render(width, height), "\r\n"))
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			m.handleKey(k)
+		case <-tick:
+			m.reload = true
+		case r := <-results:
+			m.setResult(r)
+		}
+
+		if m.quit {
+			return nil
+		}
+
+		// reload while loading is done by the running load
+		if m.reload {
+			m.reload = false
+			if !m.loading {
+				m.loading = true
+				go func() {
+					results <- o.load(args)
+				}()
+			}
+		}
+	}
+}
+
+// load fetches nodes and pods
+// Nodes which fail with --continue-on-error are reported as error of each load.
+func (o *TuiOptions) load(args []string) tuiResult {
+
+	o.nodeErrors = nil
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return tuiResult{err: err}
+	}
+
+	pl, err := o.podClient.List(metav1.ListOptions{})
+	if err != nil {
+		return tuiResult{err: fmt.Errorf("failed to get pods: %v", err)}
+	}
+
+	r := tuiResult{nodes: nodes, pods: pl.Items, loaded: true}
+	if len(o.nodeErrors) > 0 {
+		r.err = fmt.Errorf("failed to get %d node(s) (e.g. %s: %v)", len(o.nodeErrors), o.nodeErrors[0].name, o.nodeErrors[0].err)
+	}
+
+	return r
+}
+
+// setResult updates model by result of load
+// Data is kept if fetching fails, so the error is shown with the last data.
+func (m *tuiModel) setResult(r tuiResult) {
+
+	m.loading = false
+	m.err = r.err
+	if r.loaded {
+		m.setData(r.nodes, r.pods)
+	}
+}
+
+// getTerminalSize returns width and height of terminal
+// Default size is used if output is not a terminal.
+func (o *TuiOptions) getTerminalSize() (int, int) {
+
+	f, ok := o.Out.(*os.File)
+	if !ok {
+		return 80, 24
+	}
+
+	size := term.GetSize(f.Fd())
+	if size == nil {
+		return 80, 24
+	}
+
+	return int(size.Width), int(size.Height)
+}
+
+// readTuiKeys sends keys read from r to keys
+// keys is closed when r is closed.
+func readTuiKeys(r io.Reader, keys chan<- string) {
+
+	defer close(keys)
+
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, k := range parseTuiKeys(buf[:n]) {
+			keys <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseTuiKeys returns names of keys in input
+// Special keys are named (e.g. "up", "enter") and others are characters.
+func parseTuiKeys(b []byte) []string {
+
+	sequences := []struct {
+		seq  string
+		name string
+	}{
+		{"\x1b[A", "up"},
+		{"\x1b[B", "down"},
+		{"\x1b[C", "right"},
+		{"\x1b[D", "left"},
+		{"\x1b", "esc"},
+		{"\r", "enter"},
+		{"\n", "enter"},
+		{"\x7f", "backspace"},
+		{"\b", "backspace"},
+		{"\x03", "ctrl-c"},
+	}
+
+	keys := []string{}
+	s := string(b)
+	for len(s) > 0 {
+		found := false
+		for _, q := range sequences {
+			if strings.HasPrefix(s, q.seq) {
+				keys = append(keys, q.name)
+				s = s[len(q.seq):]
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		keys = append(keys, string(r))
+		s = s[size:]
+	}
+
+	return keys
+}
+
+// setData replaces nodes and pods of model
+// Nodes are sorted by usage and images are sorted by size.
+func (m *tuiModel) setData(nodes []v1.Node, pods []v1.Pod) {
+
+	m.nodes = []tuiNode{}
+	for _, node := range nodes {
+		capacity, _ := node.Status.Capacity.StorageEphemeral().AsInt64()
+		images := append([]v1.ContainerImage{}, m.o.filterImages(node.Status.Images)...)
+		sort.SliceStable(images, func(i, j int) bool {
+			return images[i].SizeBytes > images[j].SizeBytes
+		})
+		used, _ := util.GetImageUsage(images)
+
+		m.nodes = append(m.nodes, tuiNode{
+			name:     node.ObjectMeta.Name,
+			used:     used,
+			capacity: capacity,
+			images:   images,
+		})
+	}
+
+	sort.SliceStable(m.nodes, func(i, j int) bool {
+		pi := getPercent(m.nodes[i].used, m.nodes[i].capacity)
+		pj := getPercent(m.nodes[j].used, m.nodes[j].capacity)
+		if pi != pj {
+			return pi > pj
+		}
+		return m.nodes[i].used > m.nodes[j].used
+	})
+
+	m.imageNodeIndex = map[string][]int{}
+	for i, n := range m.nodes {
+		for _, image := range n.images {
+			for _, name := range image.Names {
+				key := util.NormalizeImageName(name)
+				indexes := m.imageNodeIndex[key]
+				if len(indexes) == 0 || indexes[len(indexes)-1] != i {
+					m.imageNodeIndex[key] = append(indexes, i)
+				}
+			}
+		}
+	}
+
+	m.pods = pods
+	m.clampCursor()
+}
+
+// findNode returns node in model by name
+func (m *tuiModel) findNode(name string) (tuiNode, bool) {
+	for _, n := range m.nodes {
+		if n.name == name {
+			return n, true
+		}
+	}
+	return tuiNode{}, false
+}
+
+// rows returns rows of current view which match with filter
+func (m *tuiModel) rows() ([]table.Column, []tuiRow) {
+
+	var columns []table.Column
+	rows := []tuiRow{}
+
+	switch m.view {
+	case tuiNodes:
+		// name is the last column to be truncated to the terminal width
+		columns = []table.Column{
+			{Name: "IMAGE USED", Type: table.Bytes},
+			{Name: "CAPACITY", Type: table.Bytes},
+			{Name: "%USED", Type: table.Percent},
+			{Name: "USAGE", Type: table.String},
+			{Name: "IMAGES", Type: table.Count},
+			{Name: "NAME", Type: table.String},
+		}
+		for _, n := range m.nodes {
+			rows = append(rows, tuiRow{
+				cells: []table.Cell{
+					m.o.bytesCell(n.used),
+					m.o.bytesCell(n.capacity),
+					m.o.percentCell(n.used, n.capacity, m.o.warnThreshold, m.o.critThreshold),
+					{Text: m.o.getUsageBar(n.used, n.capacity, m.o.warnThreshold, m.o.critThreshold)},
+					countCell(len(n.images)),
+					{Text: n.name},
+				},
+				key:  n.name,
+				text: n.name,
+			})
+		}
+
+	case tuiImages:
+		columns = []table.Column{
+			{Name: "IMAGE SIZE", Type: table.Bytes},
+			{Name: "NODES", Type: table.Count},
+			{Name: "IMAGE NAME", Type: table.String},
+		}
+		node, _ := m.findNode(m.node)
+		for i, image := range node.images {
+			name := util.GetImageName(image)
+			rows = append(rows, tuiRow{
+				cells: []table.Cell{m.o.bytesCell(image.SizeBytes), countCell(len(m.imageNodes(image))), {Text: name}},
+				key:   strconv.Itoa(i),
+				text:  strings.Join(image.Names, " "),
+			})
+		}
+
+	case tuiImage:
+		columns = []table.Column{
+			{Name: "KIND", Type: table.String},
+			{Name: "NODE", Type: table.String},
+			{Name: "NAME", Type: table.String},
+		}
+		for _, n := range m.imageNodes(m.image) {
+			rows = append(rows, tuiRow{
+				cells: []table.Cell{{Text: "node"}, {Text: n}, {Text: n}},
+				key:   n,
+				text:  n,
+			})
+		}
+		for _, pod := range m.imagePods(m.image) {
+			name := pod.ObjectMeta.Namespace + "/" + pod.ObjectMeta.Name
+			node := pod.Spec.NodeName
+			if node == "" {
+				node = "<none>"
+			}
+			rows = append(rows, tuiRow{
+				cells: []table.Cell{{Text: "pod"}, {Text: node}, {Text: name}},
+				text:  name + " " + node,
+			})
+		}
+	}
+
+	if m.filter == "" {
+		return columns, rows
+	}
+
+	filtered := []tuiRow{}
+	for _, r := range rows {
+		if strings.Contains(strings.ToLower(r.text), strings.ToLower(m.filter)) {
+			filtered = append(filtered, r)
+		}
+	}
+	return columns, filtered
+}
+
+// imageNodes returns names of nodes which have image
+// Nodes are in the same order as nodes view.
+func (m *tuiModel) imageNodes(image v1.ContainerImage) []string {
+
+	found := map[int]bool{}
+	for _, name := range image.Names {
+		for _, i := range m.imageNodeIndex[util.NormalizeImageName(name)] {
+			found[i] = true
+		}
+	}
+
+	indexes := []int{}
+	for i := range found {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	nodes := []string{}
+	for _, i := range indexes {
+		nodes = append(nodes, m.nodes[i].name)
+	}
+	return nodes
+}
+
+// imagePods returns pods which use image
+// Image id (digest) is used if image name does not match, same as pods command.
+func (m *tuiModel) imagePods(image v1.ContainerImage) []v1.Pod {
+
+	images := []v1.ContainerImage{image}
+	pods := []v1.Pod{}
+	for _, pod := range m.pods {
+		ids := util.GetPodImageIDs(pod)
+		for _, name := range util.GetPodImages(pod) {
+			if util.FindImageIndex(images, name) >= 0 || (ids.Get(name) != "" && util.FindImageIndex(images, ids.Get(name)) >= 0) {
+				pods = append(pods, pod)
+				break
+			}
+		}
+	}
+	return pods
+}
+
+// handleKey updates model by key
+func (m *tuiModel) handleKey(k string) {
+
+	// typing filter
+	if m.filtering {
+		switch k {
+		case "enter":
+			m.filtering = false
+		case "esc":
+			m.filtering = false
+			m.filter = ""
+		case "backspace":
+			if r := []rune(m.filter); len(r) > 0 {
+				m.filter = string(r[:len(r)-1])
+			}
+		case "ctrl-c":
+			m.quit = true
+		default:
+			if len([]rune(k)) == 1 {
+				m.filter += k
+			}
+		}
+		m.clampCursor()
+		return
+	}
+
+	switch k {
+	case "q", "ctrl-c":
+		m.quit = true
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		m.cursor++
+		m.clampCursor()
+	case "enter", "right", "l":
+		m.open()
+	case "esc", "left", "h", "backspace":
+		m.back()
+	case "/":
+		m.filtering = true
+	case "r":
+		m.reload = true
+	}
+}
+
+// open shows selected row in next view
+func (m *tuiModel) open() {
+
+	_, rows := m.rows()
+	if m.cursor >= len(rows) || rows[m.cursor].key == "" {
+		return
+	}
+	key := rows[m.cursor].key
+
+	m.history = append(m.history, m.tuiState)
+	switch m.view {
+	case tuiNodes, tuiImage:
+		m.node = key
+		m.view = tuiImages
+	case tuiImages:
+		node, _ := m.findNode(m.node)
+		i, _ := strconv.Atoi(key)
+		m.image = node.images[i]
+		m.view = tuiImage
+	}
+
+	m.cursor = 0
+	m.filter = ""
+}
+
+// back shows previous view
+func (m *tuiModel) back() {
+
+	if len(m.history) == 0 {
+		m.filter = ""
+		m.clampCursor()
+		return
+	}
+
+	m.tuiState = m.history[len(m.history)-1]
+	m.history = m.history[:len(m.history)-1]
+	m.clampCursor()
+}
+
+// clampCursor keeps cursor in rows
+func (m *tuiModel) clampCursor() {
+
+	_, rows := m.rows()
+	if m.cursor >= len(rows) {
+		m.cursor = len(rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// title returns title of current view
+func (m *tuiModel) title() string {
+	switch m.view {
+	case tuiImages:
+		return "Images on " + m.node
+	case tuiImage:
+		return "Nodes and pods of " + util.GetImageName(m.image)
+	default:
+		return "Nodes"
+	}
+}
+
+// render returns lines of screen
+// Rows are scrolled to show cursor, and cursor row is reversed.
+func (m *tuiModel) render(width, height int) []string {
+
+	columns, rows := m.rows()
+
+	buffer := &bytes.Buffer{}
+	t := table.NewOutputTable(buffer)
+	t.MaxWidth = width
+	t.AddColumns(columns)
+	for _, r := range rows {
+		t.AddCells(r.cells)
+	}
+	// writing to buffer never fails
+	_ = t.Print()
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+
+	// title, header and status lines are fixed
+	size := height - 3
+	if size < 1 {
+		size = 1
+	}
+	offset := 0
+	if m.cursor >= size {
+		offset = m.cursor - size + 1
+	}
+
+	screen := []string{"kubectl dfi - " + m.title(), lines[0]}
+	for i := offset; i < len(rows) && i < offset+size; i++ {
+		line := lines[i+1]
+		if i == m.cursor {
+			// colors reset reverse, so it is set again after them
+			line = tuiReverse + strings.Replace(line, tuiReset, tuiReset+tuiReverse, -1) + tuiReset
+		}
+		screen = append(screen, line)
+	}
+	for len(screen) < height-1 {
+		screen = append(screen, "")
+	}
+
+	return append(screen, m.status())
+}
+
+// status returns status line with filter, loading, error or help
+func (m *tuiModel) status() string {
+	switch {
+	case m.filtering:
+		return "/" + m.filter
+	case m.loading:
+		return "loading..."
+	case m.err != nil:
+		return "error: " + m.err.Error()
+	case m.filter != "":
+		return "filter: " + m.filter + "  (Esc to clear)"
+	default:
+		return "Enter: open  Esc: back  /: filter  r: refresh  q: quit"
+	}
+}
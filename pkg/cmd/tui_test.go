@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// testTuiModel returns model of test nodes and pods
+func testTuiModel() *tuiModel {
+	m := &tuiModel{o: &DfiOptions{nocolor: true, warnThreshold: 25, critThreshold: 50}}
+	m.setData(testNodes, testPods)
+	return m
+}
+
+// testTuiRowKeys returns keys of rows in current view
+func testTuiRowKeys(m *tuiModel) []string {
+	_, rows := m.rows()
+	keys := []string{}
+	for _, r := range rows {
+		keys = append(keys, r.key)
+	}
+	return keys
+}
+
+func TestNewTuiOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &TuiOptions{
+		DfiOptions: dfi,
+		refresh:    30 * time.Second,
+	}
+
+	actual := NewTuiOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestTuiValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		refresh     time.Duration
+		expected    string
+	}{
+		{"refresh", 10 * time.Second, ""},
+		{"no refresh", 0, ""},
+		{"negative refresh", -time.Second, "--refresh must not be negative: -1s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &TuiOptions{
+				DfiOptions: &DfiOptions{warnThreshold: 25, critThreshold: 50},
+				refresh:    test.refresh,
+			}
+			actual := o.Validate()
+			if (actual == nil && test.expected != "") || (actual != nil && actual.Error() != test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestTuiRunWithoutTerminal(t *testing.T) {
+
+	o := &TuiOptions{
+		DfiOptions: &DfiOptions{IOStreams: genericclioptions.IOStreams{In: nil}},
+	}
+
+	err := o.Run([]string{})
+	if err == nil || err.Error() != "tui requires a terminal" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseTuiKeys(t *testing.T) {
+
+	expected := []string{"up", "down", "j", "enter", "/", "n", "ö", "backspace", "ctrl-c", "esc"}
+
+	actual := parseTuiKeys([]byte("\x1b[A\x1b[Bj\r/nö\x7f\x03\x1b"))
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestTuiModelNavigation(t *testing.T) {
+
+	m := testTuiModel()
+
+	var tests = []struct {
+		description  string
+		keys         []string
+		expectedView tuiView
+		expectedKeys []string
+	}{
+		// nodes are sorted by usage
+		{"nodes", []string{}, tuiNodes, []string{"node2", "node1"}},
+		{"images of node", []string{"enter"}, tuiImages, []string{"0"}},
+		// image1 is on both nodes sorted by usage and used by pod2 and pod3
+		{"nodes and pods of image", []string{"enter"}, tuiImage, []string{"node2", "node1", "", ""}},
+		{"pod is not opened", []string{"down", "down", "enter"}, tuiImage, []string{"node2", "node1", "", ""}},
+		{"images of node in image", []string{"up", "up", "enter"}, tuiImages, []string{"0"}},
+		{"back to image", []string{"esc"}, tuiImage, []string{"node2", "node1", "", ""}},
+		{"back to nodes", []string{"left", "h"}, tuiNodes, []string{"node2", "node1"}},
+		{"stay in nodes", []string{"esc"}, tuiNodes, []string{"node2", "node1"}},
+		{"filter", []string{"/", "n", "o", "d", "e", "1", "enter"}, tuiNodes, []string{"node1"}},
+		{"clear filter", []string{"esc"}, tuiNodes, []string{"node2", "node1"}},
+	}
+
+	for _, test := range tests {
+		for _, k := range test.keys {
+			m.handleKey(k)
+		}
+
+		if m.view != test.expectedView {
+			t.Errorf("[%s] expected view(%d) differ (got: %d)", test.description, test.expectedView, m.view)
+		}
+
+		actual := testTuiRowKeys(m)
+		if !reflect.DeepEqual(actual, test.expectedKeys) {
+			t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expectedKeys, actual)
+		}
+	}
+
+	// cursor is restored by going back
+	m = testTuiModel()
+	for _, k := range []string{"down", "enter", "esc"} {
+		m.handleKey(k)
+	}
+	if m.cursor != 1 || m.node != "" {
+		t.Errorf("unexpected state: %#v", m.tuiState)
+	}
+
+	// cursor does not go out of rows
+	for _, k := range []string{"j", "j", "j"} {
+		m.handleKey(k)
+	}
+	if m.cursor != 1 {
+		t.Errorf("expected cursor(1) differ (got: %d)", m.cursor)
+	}
+
+	for _, k := range []string{"r", "q"} {
+		m.handleKey(k)
+	}
+	if !m.reload || !m.quit {
+		t.Errorf("expected reload and quit (got: %#v)", m)
+	}
+}
+
+func TestTuiRender(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		keys        []string
+		height      int
+		expected    []string
+	}{
+		{
+			"nodes",
+			[]string{},
+			6,
+			[]string{
+				"kubectl dfi - Nodes",
+				"IMAGE USED    CAPACITY   %USED   USAGE                    IMAGES   NAME",
+				"\x1b[7m        2K   10000000K      0%   [#...................]        1   node2\x1b[0m",
+				"        1K   10000000K      0%   [#...................]        1   node1",
+				"",
+				"Enter: open  Esc: back  /: filter  r: refresh  q: quit",
+			},
+		},
+		{
+			"scroll to cursor",
+			[]string{"down"},
+			4,
+			[]string{
+				"kubectl dfi - Nodes",
+				"IMAGE USED    CAPACITY   %USED   USAGE                    IMAGES   NAME",
+				"\x1b[7m        1K   10000000K      0%   [#...................]        1   node1\x1b[0m",
+				"Enter: open  Esc: back  /: filter  r: refresh  q: quit",
+			},
+		},
+		{
+			"typing filter",
+			[]string{"/", "x"},
+			4,
+			[]string{
+				"kubectl dfi - Nodes",
+				"IMAGE USED   CAPACITY   %USED   USAGE   IMAGES   NAME",
+				"",
+				"/x",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			m := testTuiModel()
+			for _, k := range test.keys {
+				m.handleKey(k)
+			}
+
+			actual := m.render(80, test.height)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%#v) differ (got: %#v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTuiImageNodes(t *testing.T) {
+
+	m := testTuiModel()
+
+	var tests = []struct {
+		description string
+		image       v1.ContainerImage
+		expected    []string
+	}{
+		{"on all nodes", v1.ContainerImage{Names: []string{"image1"}}, []string{"node2", "node1"}},
+		{"normalized name", v1.ContainerImage{Names: []string{"docker.io/library/image2:latest"}}, []string{"node1"}},
+		{"any name", v1.ContainerImage{Names: []string{"image9", "image2"}}, []string{"node1"}},
+		{"not found", v1.ContainerImage{Names: []string{"image9"}}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := m.imageNodes(test.image)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTuiLoad(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testNodes[0], &testPods[0])
+
+	o := &TuiOptions{
+		DfiOptions: &DfiOptions{
+			nocolor:         true,
+			concurrency:     1,
+			continueOnError: true,
+			nodeClient:      fakeClient.CoreV1().Nodes(),
+		},
+		podClient: fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+	}
+	m := &tuiModel{o: o.DfiOptions}
+
+	// errors of nodes are not accumulated by reloads
+	expected := `failed to get 1 node(s) (e.g. node9: nodes "node9" not found)`
+	for i := 0; i < 2; i++ {
+		m.setResult(o.load([]string{"node1", "node9"}))
+		if m.err == nil || m.err.Error() != expected {
+			t.Errorf("[load %d] expected(%s) differ (got: %v)", i, expected, m.err)
+			return
+		}
+		if len(o.nodeErrors) != 1 || len(m.nodes) != 1 {
+			t.Errorf("[load %d] expected 1 error and 1 node (got: %d errors, %d nodes)", i, len(o.nodeErrors), len(m.nodes))
+			return
+		}
+	}
+}
+
+func TestTuiLoop(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testNodes[0], &testPods[0])
+
+	in, keys := io.Pipe()
+	buffer := &bytes.Buffer{}
+	o := &TuiOptions{
+		DfiOptions: &DfiOptions{
+			IOStreams:   genericclioptions.IOStreams{In: in, Out: buffer},
+			nocolor:     true,
+			concurrency: 1,
+			nodeClient:  fakeClient.CoreV1().Nodes(),
+		},
+		podClient: fakeClient.CoreV1().Pods(metav1.NamespaceAll),
+	}
+	m := &tuiModel{o: o.DfiOptions}
+
+	done := make(chan error)
+	go func() {
+		done <- o.loop(m, []string{})
+	}()
+
+	// screen is drawn with loading status before load finishes
+	keys.Write([]byte("r"))
+	keys.Write([]byte("q"))
+
+	err := <-done
+	keys.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !strings.Contains(buffer.String(), "\r\nloading...") {
+		t.Errorf("loading status is not drawn (got: %q)", buffer.String())
+	}
+}
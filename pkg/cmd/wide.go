@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// diskEventReasons defines reasons of kubelet events about disk
+var diskEventReasons = []string{"FreeDiskSpaceFailed", "ImageGCFailed"}
+
+// wideColumns defines columns of wide output
+var wideColumns = []table.Column{
+	{Name: "DISKPRESSURE", Type: table.String},
+	{Name: "LAST TRANSITION", Type: table.String},
+	{Name: "DISK TAINTS", Type: table.String},
+	{Name: "EVICTED", Type: table.Count},
+	{Name: "DISK EVENTS", Type: table.Count},
+}
+
+// nodeDiskInfo is disk related status of a node
+type nodeDiskInfo struct {
+	diskPressure   string
+	lastTransition string
+	taints         []string
+	evicted        int
+	events         int32
+}
+
+// getNodeDiskInfo returns disk related status of nodes for wide output
+func (o *DfiOptions) getNodeDiskInfo(nodes []v1.Node) (map[string]*nodeDiskInfo, error) {
+
+	infos := map[string]*nodeDiskInfo{}
+	for _, node := range nodes {
+		info := &nodeDiskInfo{diskPressure: "Unknown", lastTransition: "<none>"}
+
+		for _, c := range node.Status.Conditions {
+			if c.Type == v1.NodeDiskPressure {
+				info.diskPressure = string(c.Status)
+				info.lastTransition = c.LastTransitionTime.UTC().Format(time.RFC3339)
+			}
+		}
+
+		for _, t := range node.Spec.Taints {
+			if t.Key == "node.kubernetes.io/disk-pressure" {
+				info.taints = append(info.taints, t.Key+":"+string(t.Effect))
+			}
+		}
+
+		infos[node.ObjectMeta.Name] = info
+	}
+
+	// pods evicted by ephemeral storage
+	pl, plerr := o.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: "status.phase=Failed"})
+	if plerr != nil {
+		return nil, fmt.Errorf("failed to get pods: %v", plerr)
+	}
+	for _, pod := range pl.Items {
+		info := infos[pod.Spec.NodeName]
+		if info == nil || pod.Status.Reason != "Evicted" || !strings.Contains(pod.Status.Message, "ephemeral") {
+			continue
+		}
+		info.evicted++
+	}
+
+	// disk events in window
+	since := time.Now().Add(-o.eventsWindow)
+	for _, reason := range diskEventReasons {
+		selector := "involvedObject.kind=Node,reason=" + reason
+		el, elerr := o.clientset.CoreV1().Events(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: selector})
+		if elerr != nil {
+			return nil, fmt.Errorf("failed to get events: %v", elerr)
+		}
+		for _, e := range el.Items {
+			info := infos[e.Source.Host]
+			if info == nil || e.Reason != reason {
+				continue
+			}
+			info.events += countEventsSince(e, since)
+		}
+	}
+
+	return infos, nil
+}
+
+// countEventsSince returns number of occurrences of event since the time
+// Aggregated events have count of their lifetime, and times of occurrences
+// between first and last are unknown. Only the last one is counted if the
+// event started before the time.
+func countEventsSince(e v1.Event, since time.Time) int32 {
+
+	if e.LastTimestamp.Time.Before(since) {
+		return 0
+	}
+	if e.Count > 1 && !e.FirstTimestamp.Time.Before(since) {
+		return e.Count
+	}
+	return 1
+}
+
+// columns returns wide columns of node
+func (i *nodeDiskInfo) columns() []table.Cell {
+
+	taints := "<none>"
+	if len(i.taints) > 0 {
+		taints = strings.Join(i.taints, ",")
+	}
+
+	return []table.Cell{
+		{Text: i.diskPressure},
+		{Text: i.lastTransition},
+		{Text: taints},
+		countCell(i.evicted),
+		countCell(int(i.events)),
+	}
+}
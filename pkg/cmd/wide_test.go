@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// getTestWideObjects returns node, pods and events for wide output
+func getTestWideObjects() (v1.Node, []v1.Pod, []v1.Event) {
+
+	node := *testNodes[0].DeepCopy()
+	node.Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionTrue},
+		{
+			Type:               v1.NodeDiskPressure,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+	node.Spec.Taints = []v1.Taint{
+		{Key: "node.kubernetes.io/disk-pressure", Effect: v1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "foo", Effect: v1.TaintEffectNoSchedule},
+	}
+
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "evicted1", Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: "node1"},
+			Status: v1.PodStatus{
+				Phase:   v1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: ephemeral-storage.",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "evicted2", Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: "node1"},
+			Status: v1.PodStatus{
+				Phase:   v1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: memory.",
+			},
+		},
+	}
+
+	now := metav1.Now()
+	events := []v1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "e1", Namespace: "default"},
+			Reason:         "FreeDiskSpaceFailed",
+			Source:         v1.EventSource{Host: "node1"},
+			Count:          3,
+			FirstTimestamp: metav1.NewTime(now.Add(-10 * time.Minute)),
+			LastTimestamp:  now,
+		},
+		{
+			ObjectMeta:    metav1.ObjectMeta{Name: "e2", Namespace: "default"},
+			Reason:        "ImageGCFailed",
+			Source:        v1.EventSource{Host: "node1"},
+			LastTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+		},
+		{
+			ObjectMeta:    metav1.ObjectMeta{Name: "e3", Namespace: "default"},
+			Reason:        "Pulled",
+			Source:        v1.EventSource{Host: "node1"},
+			LastTimestamp: now,
+		},
+	}
+
+	return node, pods, events
+}
+
+func TestGetNodeDiskInfo(t *testing.T) {
+
+	node, pods, events := getTestWideObjects()
+	fakeClient := fake.NewSimpleClientset(&pods[0], &pods[1], &events[0], &events[1], &events[2])
+
+	o := &DfiOptions{
+		clientset:    fakeClient,
+		eventsWindow: time.Hour,
+	}
+
+	expected := map[string]*nodeDiskInfo{
+		"node1": {
+			diskPressure:   "True",
+			lastTransition: "2019-06-01T00:00:00Z",
+			taints:         []string{"node.kubernetes.io/disk-pressure:NoSchedule"},
+			evicted:        1,
+			events:         3,
+		},
+		"node2": {
+			diskPressure:   "Unknown",
+			lastTransition: "<none>",
+		},
+	}
+
+	actual, err := o.getNodeDiskInfo([]v1.Node{node, testNodes[1]})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+
+	// events are filtered by server
+	selectors := []string{}
+	for _, a := range fakeClient.Actions() {
+		if l, ok := a.(k8stesting.ListAction); ok && a.GetResource().Resource == "events" {
+			selectors = append(selectors, l.GetListRestrictions().Fields.String())
+		}
+	}
+	expectedSelectors := []string{
+		"involvedObject.kind=Node,reason=FreeDiskSpaceFailed",
+		"involvedObject.kind=Node,reason=ImageGCFailed",
+	}
+	if !reflect.DeepEqual(selectors, expectedSelectors) {
+		t.Errorf("expected(%v) differ (got: %v)", expectedSelectors, selectors)
+	}
+}
+
+func TestCountEventsSince(t *testing.T) {
+
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	at := func(d time.Duration) metav1.Time { return metav1.NewTime(now.Add(-d)) }
+
+	var tests = []struct {
+		description string
+		event       v1.Event
+		expected    int32
+	}{
+		{"single", v1.Event{LastTimestamp: at(time.Minute)}, 1},
+		{"out of window", v1.Event{Count: 5, FirstTimestamp: at(3 * time.Hour), LastTimestamp: at(2 * time.Hour)}, 0},
+		{"aggregated in window", v1.Event{Count: 5, FirstTimestamp: at(30 * time.Minute), LastTimestamp: at(time.Minute)}, 5},
+		{"aggregated across window", v1.Event{Count: 5, FirstTimestamp: at(3 * time.Hour), LastTimestamp: at(time.Minute)}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := countEventsSince(test.event, since)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDfiWide(t *testing.T) {
+
+	node, pods, events := getTestWideObjects()
+	fakeClient := fake.NewSimpleClientset(&pods[0], &pods[1], &events[0], &events[1], &events[2])
+
+	buffer := &bytes.Buffer{}
+	o := &DfiOptions{
+		nocolor:      true,
+		output:       "wide",
+		eventsWindow: time.Hour,
+		table:        table.NewOutputTable(buffer),
+		clientset:    fakeClient,
+	}
+
+	if err := o.dfi([]v1.Node{node, testNodes[1]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"NAME    IMAGE USED   ALLOCATABLE    CAPACITY   %USED   DISKPRESSURE   LAST TRANSITION        DISK TAINTS                                   EVICTED   DISK EVENTS",
+		"node1           1K      5000000K   10000000K      0%   True           2019-06-01T00:00:00Z   node.kubernetes.io/disk-pressure:NoSchedule         1             3",
+		"node2           2K      5000000K   10000000K      0%   Unknown        <none>                 <none>                                              0             0",
+		"",
+	}, "\n")
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
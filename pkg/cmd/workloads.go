@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// workloadsLong defines long description
+	workloadsLong = templates.LongDesc(`
+		Show disk usage of images of Deployments, StatefulSets and DaemonSets.
+
+		CACHED is number of nodes which have all images of the workload.
+		CACHE USED is total size of images of the workload on all nodes.
+		TO PULL is size of images to be pulled if the workload is scaled to
+		every node matched by its nodeSelector. Affinity and taints are not
+		considered.
+	`)
+
+	// workloadsExample defines command examples
+	workloadsExample = templates.Examples(`
+		# Show image usage of workloads in current namespace.
+		kubectl dfi workloads
+
+		# Show image usage of workloads in all namespaces.
+		kubectl dfi workloads -A
+
+		# Export image usage of workloads as tsv.
+		kubectl dfi workloads -A -o tsv
+	`)
+)
+
+// WorkloadsOptions is struct of workloads options
+type WorkloadsOptions struct {
+	*DfiOptions
+
+	// workloads options
+	namespace     string
+	allNamespaces bool
+
+	// k8s apps clients
+	deploymentClient  clientappsv1.DeploymentInterface
+	statefulSetClient clientappsv1.StatefulSetInterface
+	daemonSetClient   clientappsv1.DaemonSetInterface
+}
+
+// workload is a controller which has pod template
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+	template  v1.PodTemplateSpec
+}
+
+// workloadUsage is image usage of a workload
+type workloadUsage struct {
+	workload
+	images      int
+	cachedNodes int
+	cacheBytes  int64
+	pullBytes   int64
+}
+
+// NewWorkloadsOptions is an instance of WorkloadsOptions
+func NewWorkloadsOptions(dfi *DfiOptions) *WorkloadsOptions {
+	return &WorkloadsOptions{
+		DfiOptions:    dfi,
+		allNamespaces: false,
+	}
+}
+
+// NewCmdWorkloads is a cobra command wrapping
+func NewCmdWorkloads(dfi *DfiOptions) *cobra.Command {
+	o := NewWorkloadsOptions(dfi)
+
+	cmd := &cobra.Command{
+		Use:     "workloads",
+		Short:   "Show disk usage of images of workloads.",
+		Long:    workloadsLong,
+		Example: workloadsExample,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+
+			if err := o.Prepare(); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// bool options
+	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "A", o.allNamespaces, `List workloads across all namespaces.`)
+
+	// string option
+	cmd.Flags().StringVarP(&o.output, "output", "o", o.output, `Output format. One of `+strings.Join(tableOutputs, "|")+`.`)
+
+	return cmd
+}
+
+// Prepare sets client
+func (o *WorkloadsOptions) Prepare() error {
+
+	if err := o.DfiOptions.Prepare(); err != nil {
+		return err
+	}
+
+	ns, err := o.getNamespace(o.allNamespaces)
+	if err != nil {
+		return err
+	}
+	o.namespace = ns
+
+	o.deploymentClient = o.clientset.AppsV1().Deployments(o.namespace)
+	o.statefulSetClient = o.clientset.AppsV1().StatefulSets(o.namespace)
+	o.daemonSetClient = o.clientset.AppsV1().DaemonSets(o.namespace)
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *WorkloadsOptions) Validate() error {
+
+	if err := o.DfiOptions.Validate(); err != nil {
+		return err
+	}
+
+	return o.validateTableOutput()
+}
+
+// Run printing image usage of workloads
+func (o *WorkloadsOptions) Run(args []string) error {
+
+	workloads, err := o.getWorkloads()
+	if err != nil {
+		return err
+	}
+
+	// get nodes
+	// --selector selects workloads, so nodes are filtered only by node filters.
+	nodes, err := o.getNodesWithoutSelector()
+	if err != nil {
+		return err
+	}
+
+	sizes := getKnownImageSizes(nodes)
+	usages := []workloadUsage{}
+	for _, w := range workloads {
+		usages = append(usages, getWorkloadUsage(w, nodes, sizes))
+	}
+
+	// bigger cache first
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].cacheBytes > usages[j].cacheBytes
+	})
+
+	// set printer header
+	columns := []table.Column{
+		{Name: "KIND", Type: table.String},
+		{Name: "NAME", Type: table.String},
+		{Name: "IMAGES", Type: table.Count},
+		{Name: "CACHED", Type: table.String},
+		{Name: "CACHE USED", Type: table.Bytes},
+		{Name: "TO PULL", Type: table.Bytes},
+	}
+	if o.allNamespaces {
+		columns = append([]table.Column{{Name: "NAMESPACE", Type: table.String}}, columns...)
+	}
+	o.setTableFormat()
+	o.table.AddColumns(columns)
+
+	for _, u := range usages {
+		row := []table.Cell{
+			{Text: u.kind},
+			{Text: u.name},
+			countCell(u.images),
+			{Text: fmt.Sprintf("%d/%d", u.cachedNodes, len(nodes))},
+			o.bytesCell(u.cacheBytes),
+			o.bytesCell(u.pullBytes),
+		}
+		if o.allNamespaces {
+			row = append([]table.Cell{{Text: u.namespace}}, row...)
+		}
+		o.table.AddCells(row)
+	}
+
+	return o.table.Print()
+}
+
+// getWorkloads returns deployments, statefulsets and daemonsets
+func (o *WorkloadsOptions) getWorkloads() ([]workload, error) {
+
+	opts := metav1.ListOptions{LabelSelector: o.labelSelector}
+	workloads := []workload{}
+
+	dl, dlerr := o.deploymentClient.List(opts)
+	if dlerr != nil {
+		return nil, fmt.Errorf("failed to get deployments: %v", dlerr)
+	}
+	for _, d := range dl.Items {
+		workloads = append(workloads, workload{"Deployment", d.ObjectMeta.Namespace, d.ObjectMeta.Name, d.Spec.Template})
+	}
+
+	sl, slerr := o.statefulSetClient.List(opts)
+	if slerr != nil {
+		return nil, fmt.Errorf("failed to get statefulsets: %v", slerr)
+	}
+	for _, s := range sl.Items {
+		workloads = append(workloads, workload{"StatefulSet", s.ObjectMeta.Namespace, s.ObjectMeta.Name, s.Spec.Template})
+	}
+
+	dsl, dslerr := o.daemonSetClient.List(opts)
+	if dslerr != nil {
+		return nil, fmt.Errorf("failed to get daemonsets: %v", dslerr)
+	}
+	for _, ds := range dsl.Items {
+		workloads = append(workloads, workload{"DaemonSet", ds.ObjectMeta.Namespace, ds.ObjectMeta.Name, ds.Spec.Template})
+	}
+
+	return workloads, nil
+}
+
+// getWorkloadUsage calculates cache and pull size of workload images on nodes
+// Size of image which is not found on any nodes is unknown and counted as 0.
+func getWorkloadUsage(w workload, nodes []v1.Node, sizes map[string]int64) workloadUsage {
+
+	images := util.GetPodImages(v1.Pod{Spec: w.template.Spec})
+	selector := labels.SelectorFromSet(w.template.Spec.NodeSelector)
+
+	u := workloadUsage{workload: w, images: len(images)}
+	for _, node := range nodes {
+
+		cached := len(images) > 0
+		scheduled := selector.Matches(labels.Set(node.ObjectMeta.Labels))
+
+		for _, name := range images {
+			if image, found := util.FindImage(node.Status.Images, name); found {
+				u.cacheBytes += image.SizeBytes
+				continue
+			}
+
+			cached = false
+			if scheduled {
+				u.pullBytes += sizes[util.NormalizeImageName(name)]
+			}
+		}
+
+		if cached {
+			u.cachedNodes++
+		}
+	}
+
+	return u
+}
+
+// getKnownImageSizes returns sizes of images found on any nodes
+func getKnownImageSizes(nodes []v1.Node) map[string]int64 {
+
+	sizes := map[string]int64{}
+	for _, node := range nodes {
+		for _, image := range node.Status.Images {
+			for _, name := range image.Names {
+				n := util.NormalizeImageName(name)
+				if image.SizeBytes > sizes[n] {
+					sizes[n] = image.SizeBytes
+				}
+			}
+		}
+	}
+
+	return sizes
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	fake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/makocchi-git/kubectl-dfi/pkg/table"
+)
+
+// test workload objects
+var (
+	testDeployment = appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "c1", Image: "image1"}},
+				},
+			},
+		},
+	}
+
+	testStatefulSet = appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					NodeSelector: map[string]string{"hostname": "node1"},
+					Containers:   []v1.Container{{Name: "c1", Image: "image2"}},
+				},
+			},
+		},
+	}
+
+	testDaemonSet = appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "logger", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "c1", Image: "image2"}},
+				},
+			},
+		},
+	}
+)
+
+func TestNewWorkloadsOptions(t *testing.T) {
+
+	dfi := NewDfiOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	expected := &WorkloadsOptions{
+		DfiOptions:    dfi,
+		allNamespaces: false,
+	}
+
+	actual := NewWorkloadsOptions(dfi)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestWorkloadsRun(t *testing.T) {
+
+	fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1], &testDeployment, &testStatefulSet, &testDaemonSet)
+
+	buffer := &bytes.Buffer{}
+	o := &WorkloadsOptions{
+		DfiOptions: &DfiOptions{
+			nocolor:    true,
+			table:      table.NewOutputTable(buffer),
+			nodeClient: fakeClient.CoreV1().Nodes(),
+		},
+		allNamespaces:     true,
+		deploymentClient:  fakeClient.AppsV1().Deployments(metav1.NamespaceAll),
+		statefulSetClient: fakeClient.AppsV1().StatefulSets(metav1.NamespaceAll),
+		daemonSetClient:   fakeClient.AppsV1().DaemonSets(metav1.NamespaceAll),
+	}
+
+	if err := o.Run([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"NAMESPACE     KIND          NAME     IMAGES   CACHED   CACHE USED   TO PULL",
+		"default       Deployment    web           1   2/2              3K       N/A",
+		"default       StatefulSet   db            1   1/2              1K       N/A",
+		"kube-system   DaemonSet     logger        1   1/2              1K        1K",
+		"",
+	}, "\n")
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestGetWorkloadUsage(t *testing.T) {
+
+	w := workload{
+		kind:      "DaemonSet",
+		namespace: "kube-system",
+		name:      "logger",
+		template:  testDaemonSet.Spec.Template,
+	}
+
+	expected := workloadUsage{
+		workload:    w,
+		images:      1,
+		cachedNodes: 1,
+		cacheBytes:  1000,
+		pullBytes:   1000,
+	}
+
+	actual := getWorkloadUsage(w, testNodes, getKnownImageSizes(testNodes))
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestGetKnownImageSizes(t *testing.T) {
+
+	expected := map[string]int64{
+		"docker.io/library/image1:latest": 2000,
+		"docker.io/library/image2:latest": 1000,
+	}
+
+	actual := getKnownImageSizes(testNodes)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
@@ -0,0 +1,85 @@
+// Package history stores image disk usage of nodes over time
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record is image disk usage of a node at a time
+type Record struct {
+	Time        time.Time         `json:"time"`
+	Node        string            `json:"node"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Used        int64             `json:"used"`
+	Images      int               `json:"images"`
+	Allocatable int64             `json:"allocatable"`
+	Capacity    int64             `json:"capacity"`
+}
+
+// Append appends records to history file as JSON lines
+// The file and its directory are created if they do not exist.
+func Append(path string, records []Record) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	e := json.NewEncoder(w)
+	for _, r := range records {
+		if err := e.Encode(r); err != nil {
+			return fmt.Errorf("failed to write history: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+
+	return f.Close()
+}
+
+// Load returns records in history file
+// It returns no records if the file does not exist yet.
+func Load(path string) ([]Record, error) {
+
+	records := []Record{}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %v", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+
+		var r Record
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("failed to parse history at line %d: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// directory is created
+	path := filepath.Join(dir, "dfi", "history.jsonl")
+
+	first := []Record{
+		{Time: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), Node: "node1", Labels: map[string]string{"pool": "a"}, Used: 1000, Images: 1, Allocatable: 5000, Capacity: 10000},
+		{Time: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), Node: "node2", Used: 2000, Images: 2, Allocatable: 5000, Capacity: 10000},
+	}
+	second := []Record{
+		{Time: time.Date(2019, 6, 2, 0, 0, 0, 0, time.UTC), Node: "node1", Labels: map[string]string{"pool": "a"}, Used: 1500, Images: 2, Allocatable: 5000, Capacity: 10000},
+	}
+
+	for _, records := range [][]Record{first, second} {
+		if err := Append(path, records); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	}
+
+	actual, err := Load(path)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := append(first, second...)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "kubectl-dfi")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		description string
+		content     string
+		expected    []Record
+		expectedErr string
+	}{
+		{
+			"empty lines",
+			"\n{\"time\":\"2019-06-01T00:00:00Z\",\"node\":\"node1\",\"used\":1000}\n\n",
+			[]Record{{Time: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), Node: "node1", Used: 1000}},
+			"",
+		},
+		{
+			"broken line",
+			"{\"node\":\"node1\"}\n{\"node\":\n",
+			nil,
+			"failed to parse history at line 2: unexpected end of JSON input",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			path := filepath.Join(dir, "history.jsonl")
+			if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			actual, err := Load(path)
+			if (err == nil && test.expectedErr != "") || (err != nil && err.Error() != test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%#v) differ (got: %#v)", test.description, test.expected, actual)
+			}
+		})
+	}
+
+	// missing file is empty history
+	actual, err := Load(filepath.Join(dir, "missing.jsonl"))
+	if err != nil || len(actual) != 0 {
+		t.Errorf("unexpected result: %#v, %v", actual, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package kubelet has helpers for kubelet configuration
+package kubelet
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+const (
+
+	// DefaultImageGCHighThresholdPercent is default of imageGCHighThresholdPercent
+	DefaultImageGCHighThresholdPercent = 85
+
+	// DefaultImageGCLowThresholdPercent is default of imageGCLowThresholdPercent
+	DefaultImageGCLowThresholdPercent = 80
+
+	// SignalImageFsAvailable is eviction signal for imagefs
+	SignalImageFsAvailable = "imagefs.available"
+
+	// SignalNodeFsAvailable is eviction signal for nodefs
+	SignalNodeFsAvailable = "nodefs.available"
+)
+
+// Config is disk related part of kubelet configuration
+type Config struct {
+	ImageGCHighThresholdPercent int32             `json:"imageGCHighThresholdPercent"`
+	ImageGCLowThresholdPercent  int32             `json:"imageGCLowThresholdPercent"`
+	EvictionHard                map[string]string `json:"evictionHard"`
+}
+
+// configz is response of kubelet /configz
+type configz struct {
+	KubeletConfig *Config `json:"kubeletconfig"`
+}
+
+// NewConfig returns kubelet config with default values
+func NewConfig() *Config {
+	return &Config{
+		ImageGCHighThresholdPercent: DefaultImageGCHighThresholdPercent,
+		ImageGCLowThresholdPercent:  DefaultImageGCLowThresholdPercent,
+		EvictionHard: map[string]string{
+			SignalImageFsAvailable: "15%",
+			SignalNodeFsAvailable:  "10%",
+		},
+	}
+}
+
+// GetConfigz fetches /configz of kubelet through node proxy of API server
+func GetConfigz(c rest.Interface, node string) ([]byte, error) {
+	return c.Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("configz").DoRaw()
+}
+
+// ParseConfigz parses response of /configz
+// Missing values are filled with kubelet defaults.
+func ParseConfigz(b []byte) (*Config, error) {
+
+	d := NewConfig()
+
+	// evictionHard is not merged with defaults
+	c := configz{KubeletConfig: &Config{
+		ImageGCHighThresholdPercent: d.ImageGCHighThresholdPercent,
+		ImageGCLowThresholdPercent:  d.ImageGCLowThresholdPercent,
+	}}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse configz: %v", err)
+	}
+
+	if c.KubeletConfig == nil {
+		return nil, fmt.Errorf("failed to parse configz: kubeletconfig is empty")
+	}
+
+	if c.KubeletConfig.EvictionHard == nil {
+		c.KubeletConfig.EvictionHard = d.EvictionHard
+	}
+
+	return c.KubeletConfig, nil
+}
+
+// GetEvictionThreshold returns eviction threshold of imagefs in bytes
+// Threshold of nodefs is used if imagefs is not configured.
+func (c *Config) GetEvictionThreshold(capacity int64) (int64, string, error) {
+
+	s, ok := c.EvictionHard[SignalImageFsAvailable]
+	if !ok {
+		s, ok = c.EvictionHard[SignalNodeFsAvailable]
+	}
+	if !ok {
+		return 0, "", nil
+	}
+
+	// percentage of capacity
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, s, fmt.Errorf("invalid eviction threshold: %s", s)
+		}
+		return int64(float64(capacity) * p / 100), s, nil
+	}
+
+	// quantity
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, s, fmt.Errorf("invalid eviction threshold: %s", s)
+	}
+
+	return q.Value(), s, nil
+}
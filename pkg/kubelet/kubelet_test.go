@@ -0,0 +1,123 @@
+package kubelet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewConfig(t *testing.T) {
+
+	expected := &Config{
+		ImageGCHighThresholdPercent: 85,
+		ImageGCLowThresholdPercent:  80,
+		EvictionHard: map[string]string{
+			"imagefs.available": "15%",
+			"nodefs.available":  "10%",
+		},
+	}
+
+	actual := NewConfig()
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%#v) differ (got: %#v)", expected, actual)
+	}
+}
+
+func TestParseConfigz(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		configz     string
+		expected    *Config
+		expectedErr string
+	}{
+		{
+			"full",
+			`{"kubeletconfig":{"imageGCHighThresholdPercent":90,"imageGCLowThresholdPercent":70,"evictionHard":{"imagefs.available":"1Gi"}}}`,
+			&Config{
+				ImageGCHighThresholdPercent: 90,
+				ImageGCLowThresholdPercent:  70,
+				EvictionHard:                map[string]string{"imagefs.available": "1Gi"},
+			},
+			"",
+		},
+		{
+			"defaults",
+			`{"kubeletconfig":{"podPidsLimit":-1}}`,
+			NewConfig(),
+			"",
+		},
+		{
+			"null",
+			`{"kubeletconfig":null}`,
+			nil,
+			"failed to parse configz: kubeletconfig is empty",
+		},
+		{
+			"invalid json",
+			`kubeletconfig`,
+			nil,
+			"failed to parse configz: invalid character 'k' looking for beginning of value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual, err := ParseConfigz([]byte(test.configz))
+			if (err == nil && test.expectedErr != "") || (err != nil && err.Error() != test.expectedErr) {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf(
+					"[%s] expected(%#v) differ (got: %#v)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetEvictionThreshold(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		eviction    map[string]string
+		expected    int64
+		expectedStr string
+		expectedErr bool
+	}{
+		{"imagefs percentage", map[string]string{"imagefs.available": "15%", "nodefs.available": "10%"}, 150, "15%", false},
+		{"nodefs quantity", map[string]string{"nodefs.available": "100"}, 100, "100", false},
+		{"not configured", map[string]string{}, 0, "", false},
+		{"invalid percentage", map[string]string{"imagefs.available": "a%"}, 0, "a%", true},
+		{"invalid quantity", map[string]string{"imagefs.available": "a"}, 0, "a", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := &Config{EvictionHard: test.eviction}
+			actual, actualStr, err := c.GetEvictionThreshold(1000)
+			if (err != nil) != test.expectedErr {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if actual != test.expected || actualStr != test.expectedStr {
+				t.Errorf(
+					"[%s] expected(%d, %s) differ (got: %d, %s)",
+					test.description,
+					test.expected,
+					test.expectedStr,
+					actual,
+					actualStr,
+				)
+				return
+			}
+		})
+	}
+}
@@ -1,19 +1,102 @@
 package table
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// layout of columns (same as kubectl)
+const (
+	minWidth = 6
+	padding  = 3
+)
+
+// ellipsis is inserted into truncated text
+const ellipsis = "..."
+
+// ansiEscape matches ansi color sequences which have no width
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// machine readable formats of table
+const (
+	// CSV is comma separated values
+	CSV = "csv"
+	// TSV is tab separated values
+	TSV = "tsv"
+	// Markdown is GitHub flavored markdown table
+	Markdown = "markdown"
+)
 
-	"github.com/makocchi-git/kubectl-dfi/pkg/util"
+// CellType is type of values in a column
+type CellType int
 
-	"k8s.io/kubernetes/pkg/printers"
+const (
+	// String is text
+	String CellType = iota
+	// Bytes is size in bytes
+	Bytes
+	// Percent is percentage
+	Percent
+	// Count is number of things
+	Count
+	// Number is other numeric value (e.g. cost, seconds)
+	Number
 )
 
+// IsNumeric returns true if values are numbers
+// Numeric columns are aligned to the right.
+func (c CellType) IsNumeric() bool {
+	return c == Bytes || c == Percent || c == Count || c == Number
+}
+
+// Column is metadata of a column
+type Column struct {
+	Name string
+	Type CellType
+}
+
+// Cell is a value in a table
+type Cell struct {
+	// Text is printed text
+	Text string
+	// Value is raw value of numeric cell (e.g. bytes)
+	Value int64
+	// Raw is unit-free text of numeric cell for machine readable formats
+	// It is empty if value is unknown.
+	Raw string
+	// Color decorates text after alignment and truncation
+	Color func(string) string
+	// Status is state of value (e.g. "ok", "warn", "crit") shown by markers in markdown
+	Status string
+}
+
 // OutputTable is struct of tables for outputs
 type OutputTable struct {
-	Header string
-	Rows   []string
-	Output io.Writer
+	Columns []Column
+	Rows    [][]Cell
+	Output  io.Writer
+
+	// Selected is names of columns to print in order. All columns are printed if empty.
+	Selected []string
+	// NoHeaders disables header
+	NoHeaders bool
+	// MaxWidth truncates text of the last column to fit in the width if positive
+	MaxWidth int
+	// Format prints rows as csv or tsv records or markdown table instead of aligned columns if set
+	Format string
+	// Markers are prefixed to cells by status in markdown instead of colors
+	Markers map[string]string
+	// Buffer keeps rows of aligned columns between prints until Flush
+	// Widths of columns are known only after all rows are added. Rows of
+	// csv, tsv and markdown need no widths, so they are printed by Print.
+	Buffer bool
+
+	// header is printed only at first time to stream rows
+	headerPrinted bool
 }
 
 // NewOutputTable is an instance of OutputTable
@@ -24,29 +107,295 @@ func NewOutputTable(o io.Writer) *OutputTable {
 }
 
 // Print shows table output
-func (t *OutputTable) Print() {
+// Rows are cleared after printing, so Print can be called repeatedly to
+// stream rows. Header is printed only at first time. Set Buffer and call
+// Flush at last to align columns of rows of all prints.
+func (t *OutputTable) Print() error {
+
+	if t.Buffer && t.Format == "" {
+		return nil
+	}
+	return t.print()
+}
+
+// Flush prints rows kept by Buffer
+func (t *OutputTable) Flush() error {
+	return t.print()
+}
+
+// print prints header and rows
+// It returns the first error of writing to Output.
+func (t *OutputTable) print() error {
+
+	indexes := t.selectedIndexes()
+
+	// header is a row of string cells
+	rows := [][]Cell{}
+	// markdown table can not be rendered without header
+	header := (!t.NoHeaders || t.Format == Markdown) && !t.headerPrinted
+	if header {
+		names := []Cell{}
+		for _, c := range t.Columns {
+			names = append(names, Cell{Text: c.Name})
+		}
+		rows = append(rows, names)
+		t.headerPrinted = true
+	}
+	rows = append(rows, t.Rows...)
+	t.Rows = nil
+
+	// pick selected cells
+	selected := make([][]Cell, len(rows))
+	for i, row := range rows {
+		for _, index := range indexes {
+			c := Cell{}
+			if index < len(row) {
+				c = row[index]
+			}
+			selected[i] = append(selected[i], c)
+		}
+	}
+
+	if t.Format == CSV || t.Format == TSV {
+		return t.printRecords(selected, indexes, header)
+	}
+
+	if t.Format == Markdown {
+		return t.printMarkdown(selected, indexes, header)
+	}
+
+	// widths of columns
+	widths := make([]int, len(indexes))
+	for _, row := range selected {
+		for i, c := range row {
+			if w := visibleWidth(c.Text); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	for _, row := range selected {
+		if _, err := fmt.Fprintln(t.Output, t.formatRow(row, indexes, widths)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printRecords prints rows as csv or tsv records
+// Numeric cells are printed as raw values and colors are removed.
+func (t *OutputTable) printRecords(rows [][]Cell, indexes []int, header bool) error {
+
+	w := csv.NewWriter(t.Output)
+	if t.Format == TSV {
+		w.Comma = '\t'
+	}
+
+	for i, row := range rows {
+		record := []string{}
+		for j, c := range row {
+			if t.columnType(indexes[j]).IsNumeric() && !(header && i == 0) {
+				record = append(record, c.Raw)
+				continue
+			}
+			record = append(record, ansiEscape.ReplaceAllString(c.Text, ""))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// printMarkdown prints rows as markdown table
+// Numeric columns are aligned to the right and colors are replaced with markers.
+func (t *OutputTable) printMarkdown(rows [][]Cell, indexes []int, header bool) error {
+
+	for i, row := range rows {
+		texts := []string{}
+		for _, c := range row {
+			text := ansiEscape.ReplaceAllString(c.Text, "")
+			if m := t.Markers[c.Status]; m != "" {
+				text = m + " " + text
+			}
+			text = strings.Replace(text, "|", "\\|", -1)
+			texts = append(texts, strings.Replace(text, "\n", " ", -1))
+		}
+		if _, err := fmt.Fprintln(t.Output, "| "+strings.Join(texts, " | ")+" |"); err != nil {
+			return err
+		}
 
-	// get printer
-	printer := printers.GetNewTabWriter(t.Output)
+		// delimiter row follows header
+		if header && i == 0 {
+			delimiters := []string{}
+			for _, index := range indexes {
+				if t.columnType(index).IsNumeric() {
+					delimiters = append(delimiters, "---:")
+				} else {
+					delimiters = append(delimiters, "---")
+				}
+			}
+			if _, err := fmt.Fprintln(t.Output, "| "+strings.Join(delimiters, " | ")+" |"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatRow returns a line of cells aligned by column widths
+func (t *OutputTable) formatRow(row []Cell, indexes, widths []int) string {
+
+	line := ""
+	offset := 0
+	for i, c := range row {
+		last := i == len(row)-1
+		text := c.Text
+		width := widths[i]
+
+		// fit the last column in max width
+		if last && t.MaxWidth > 0 && !t.columnType(indexes[i]).IsNumeric() {
+			text = truncate(text, t.MaxWidth-offset)
+		}
 
-	// write header
-	fmt.Fprintln(printer, t.Header)
+		// numeric columns are aligned to the right
+		pad := width - visibleWidth(text)
+		left := ""
+		if t.columnType(indexes[i]).IsNumeric() {
+			left = strings.Repeat(" ", pad)
+			pad = 0
+		}
 
-	// write rows
-	for _, row := range t.Rows {
-		fmt.Fprintln(printer, row)
+		if c.Color != nil {
+			text = c.Color(text)
+		}
+
+		if last {
+			line += left + text
+			break
+		}
+
+		// padding between columns
+		cw := width + padding
+		if cw < minWidth {
+			cw = minWidth
+		}
+		line += left + text + strings.Repeat(" ", pad+cw-width)
+		offset += cw
 	}
 
-	// finish
-	printer.Flush()
+	return line
 }
 
-// AddHeader adds row to table
+// selectedIndexes returns indexes of columns to print
+func (t *OutputTable) selectedIndexes() []int {
+
+	indexes := []int{}
+	if len(t.Selected) == 0 {
+		for i := range t.Columns {
+			indexes = append(indexes, i)
+		}
+		return indexes
+	}
+
+	for _, name := range t.Selected {
+		if i := t.columnIndex(name); i >= 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// columnIndex returns index of column (case insensitive)
+// It returns -1 if column is not found.
+func (t *OutputTable) columnIndex(name string) int {
+
+	for i, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnType returns type of column
+func (t *OutputTable) columnType(index int) CellType {
+
+	if index < len(t.Columns) {
+		return t.Columns[index].Type
+	}
+	return String
+}
+
+// SelectColumns sets columns to print in order
+func (t *OutputTable) SelectColumns(names []string) error {
+
+	valid := []string{}
+	for _, c := range t.Columns {
+		valid = append(valid, c.Name)
+	}
+
+	for _, name := range names {
+		if t.columnIndex(name) < 0 {
+			return fmt.Errorf("invalid column: %s (valid columns: %s)", name, strings.Join(valid, ", "))
+		}
+	}
+
+	t.Selected = names
+	return nil
+}
+
+// AddHeader adds string columns to table
 func (t *OutputTable) AddHeader(s []string) {
-	t.Header = util.JoinTab(s)
+
+	columns := []Column{}
+	for _, name := range s {
+		columns = append(columns, Column{Name: name, Type: String})
+	}
+	t.Columns = columns
 }
 
-// AddRow adds row to table
+// AddColumns adds typed columns to table
+func (t *OutputTable) AddColumns(c []Column) {
+	t.Columns = c
+}
+
+// AddRow adds row of string cells to table
 func (t *OutputTable) AddRow(s []string) {
-	t.Rows = append(t.Rows, util.JoinTab(s))
+
+	cells := []Cell{}
+	for _, text := range s {
+		cells = append(cells, Cell{Text: text})
+	}
+	t.Rows = append(t.Rows, cells)
+}
+
+// AddCells adds row of typed cells to table
+func (t *OutputTable) AddCells(c []Cell) {
+	t.Rows = append(t.Rows, c)
+}
+
+// visibleWidth returns width of text without color sequences
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// truncate shortens text to width by replacing the middle with ellipsis
+// Both ends are kept because registry and tag of image names are important.
+func truncate(s string, width int) string {
+
+	r := []rune(ansiEscape.ReplaceAllString(s, ""))
+	if len(r) <= width || width <= len(ellipsis)+2 {
+		return s
+	}
+
+	keep := width - len(ellipsis)
+	head := keep / 2
+	tail := keep - head
+
+	return string(r[:head]) + ellipsis + string(r[len(r)-tail:])
 }
@@ -2,8 +2,10 @@ package table
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -26,11 +28,11 @@ func TestPrint(t *testing.T) {
 
 	var tests = []struct {
 		description string
-		rows        []string
+		rows        [][]string
 		expected    string
 	}{
-		{"1 row", []string{"1\t2"}, "a     b\n1     2\n"},
-		{"2 rows", []string{"1\t2", "3\t4"}, "a     b\n1     2\n3     4\n"},
+		{"1 row", [][]string{{"1", "2"}}, "a     b\n1     2\n"},
+		{"2 rows", [][]string{{"1", "2"}, {"3", "4"}}, "a     b\n1     2\n3     4\n"},
 	}
 
 	for _, test := range tests {
@@ -38,10 +40,10 @@ func TestPrint(t *testing.T) {
 		buffer.Reset()
 
 		t.Run(test.description, func(t *testing.T) {
-			table := &OutputTable{
-				Header: "a\tb",
-				Rows:   test.rows,
-				Output: buffer,
+			table := NewOutputTable(buffer)
+			table.AddHeader([]string{"a", "b"})
+			for _, row := range test.rows {
+				table.AddRow(row)
 			}
 
 			table.Print()
@@ -63,9 +65,9 @@ func TestAddHeader(t *testing.T) {
 	table := &OutputTable{}
 	table.AddHeader([]string{"a", "b", "c"})
 
-	expected := "a\tb\tc"
-	if table.Header != expected {
-		t.Errorf("expected(%s) differ (got: %s)", expected, table.Header)
+	expected := []Column{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if !reflect.DeepEqual(table.Columns, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, table.Columns)
 	}
 }
 
@@ -74,13 +76,365 @@ func TestAddRow(t *testing.T) {
 	table.AddRow([]string{"1", "2", "3"})
 	table.AddRow([]string{"4", "5", "6"})
 
-	rows := table.Rows
+	expected := [][]Cell{
+		{{Text: "1"}, {Text: "2"}, {Text: "3"}},
+		{{Text: "4"}, {Text: "5"}, {Text: "6"}},
+	}
+	if !reflect.DeepEqual(table.Rows, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, table.Rows)
+	}
+}
+
+func TestPrintRepeatedly(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		format      string
+		first       string
+		expected    string
+	}{
+		{"aligned", "", "", "a         b\n3         4\n1234567   2\n"},
+		{"csv", CSV, "a,b\n3,4\n", "a,b\n3,4\n1234567,2\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			table := NewOutputTable(buffer)
+			table.Format = test.format
+			table.Buffer = true
+			table.AddHeader([]string{"a", "b"})
+
+			// the second chunk is wider than the first one
+			table.AddRow([]string{"3", "4"})
+			table.Print()
+			if buffer.String() != test.first {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.first, buffer.String())
+				return
+			}
+			table.AddRow([]string{"1234567", "2"})
+			table.Print()
+			table.Flush()
+
+			if buffer.String() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, buffer.String())
+				return
+			}
+
+			if len(table.Rows) != 0 {
+				t.Errorf("[%s] expected rows are cleared (got: %v)", test.description, table.Rows)
+			}
+		})
+	}
+}
+
+func TestPrintTyped(t *testing.T) {
+
+	yellow := func(s string) string { return "\x1b[33m" + s + "\x1b[0m" }
+	tag := func(s string) string {
+		i := strings.LastIndex(s, ":")
+		return s[:i+1] + yellow(s[i+1:])
+	}
+
+	columns := []Column{
+		{Name: "NAME", Type: String},
+		{Name: "SIZE", Type: Bytes},
+		{Name: "%USED", Type: Percent},
+		{Name: "IMAGE NAME", Type: String},
+	}
+	rows := [][]Cell{
+		{{Text: "node1"}, {Text: "1K", Value: 1000}, {Text: "5%", Value: 5, Color: yellow}, {Text: "docker.io/library/nginx:1.17.0", Color: tag}},
+		{{Text: "node10"}, {Text: "100000K", Value: 100000000}, {Text: "100%", Value: 100}, {Text: "busybox"}},
+	}
+
+	var tests = []struct {
+		description string
+		selected    []string
+		noHeaders   bool
+		maxWidth    int
+		expected    []string
+	}{
+		{
+			"right aligned",
+			[]string{},
+			false,
+			0,
+			[]string{
+				"NAME        SIZE   %USED   IMAGE NAME",
+				"node1         1K      " + yellow("5%") + "   docker.io/library/nginx:" + yellow("1.17.0"),
+				"node10   100000K    100%   busybox",
+				"",
+			},
+		},
+		{
+			"columns",
+			[]string{"image name", "%USED"},
+			false,
+			0,
+			[]string{
+				"IMAGE NAME                       %USED",
+				"docker.io/library/nginx:" + yellow("1.17.0") + "      " + yellow("5%"),
+				"busybox                           100%",
+				"",
+			},
+		},
+		{
+			"no headers",
+			[]string{"NAME", "SIZE"},
+			true,
+			0,
+			[]string{
+				"node1         1K",
+				"node10   100000K",
+				"",
+			},
+		},
+		{
+			"truncate",
+			[]string{},
+			false,
+			45,
+			[]string{
+				"NAME        SIZE   %USED   IMAGE NAME",
+				"node1         1K      " + yellow("5%") + "   docker....x:" + yellow("1.17.0"),
+				"node10   100000K    100%   busybox",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			table := NewOutputTable(buffer)
+			table.AddColumns(columns)
+			for _, row := range rows {
+				table.AddCells(row)
+			}
+			if err := table.SelectColumns(test.selected); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			table.NoHeaders = test.noHeaders
+			table.MaxWidth = test.maxWidth
+
+			table.Print()
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+
+	table := &OutputTable{}
+	table.AddHeader([]string{"NAME", "SIZE"})
+
+	err := table.SelectColumns([]string{"name", "AGE"})
+	expected := "invalid column: AGE (valid columns: NAME, SIZE)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected(%s) differ (got: %v)", expected, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		text        string
+		width       int
+		expected    string
+	}{
+		{"short", "nginx:1.17", 20, "nginx:1.17"},
+		{"truncated", "gcr.io/project/app:v1.2.3", 15, "gcr.io...v1.2.3"},
+		{"too narrow", "gcr.io/project/app:v1.2.3", 5, "gcr.io/project/app:v1.2.3"},
+		{"colored", "\x1b[33mgcr.io/project/app:v1.2.3\x1b[0m", 15, "gcr.io...v1.2.3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := truncate(test.text, test.width)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrintRecords(t *testing.T) {
+
+	yellow := func(s string) string { return "\x1b[33m" + s + "\x1b[0m" }
+
+	var tests = []struct {
+		description string
+		format      string
+		noHeaders   bool
+		expected    []string
+	}{
+		{
+			"csv",
+			CSV,
+			false,
+			[]string{
+				"NAME,SIZE,IMAGE NAME",
+				"node1,1000,nginx:1.17",
+				`node2,,"gcr.io/app:v1,""odd"""`,
+				"",
+			},
+		},
+		{
+			"tsv",
+			TSV,
+			false,
+			[]string{
+				"NAME\tSIZE\tIMAGE NAME",
+				"node1\t1000\tnginx:1.17",
+				`node2		"gcr.io/app:v1,""odd"""`,
+				"",
+			},
+		},
+		{
+			"no headers",
+			CSV,
+			true,
+			[]string{
+				"node1,1000,nginx:1.17",
+				`node2,,"gcr.io/app:v1,""odd"""`,
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			table := NewOutputTable(buffer)
+			table.Format = test.format
+			table.NoHeaders = test.noHeaders
+			table.AddColumns([]Column{{Name: "NAME"}, {Name: "SIZE", Type: Bytes}, {Name: "IMAGE NAME"}})
+
+			// rows are streamed without repeated header
+			table.AddCells([]Cell{{Text: "node1"}, {Text: "1K", Value: 1000, Raw: "1000"}, {Text: "nginx:" + yellow("1.17")}})
+			table.Print()
+			table.AddCells([]Cell{{Text: "node2"}, {Text: "N/A"}, {Text: `gcr.io/app:v1,"odd"`}})
+			table.Print()
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+// errWriter fails on every write
+type errWriter struct{}
 
-	if rows[0] != "1\t2\t3" {
-		t.Errorf("expected(1\t2\t3) differ (got: %s)", rows[0])
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestPrintError(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		format      string
+	}{
+		{"aligned", ""},
+		{"csv", CSV},
+		{"markdown", Markdown},
 	}
-	if rows[1] != "4\t5\t6" {
-		t.Errorf("expected(4\t5\t6) differ (got: %s)", rows[1])
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			table := NewOutputTable(errWriter{})
+			table.Format = test.format
+			table.AddHeader([]string{"NAME"})
+			table.AddRow([]string{"node1"})
+
+			err := table.Print()
+			if err == nil || err.Error() != "disk full" {
+				t.Errorf("[%s] expected(disk full) differ (got: %v)", test.description, err)
+			}
+		})
 	}
+}
+
+func TestPrintMarkdown(t *testing.T) {
+
+	yellow := func(s string) string { return "\x1b[33m" + s + "\x1b[0m" }
 
+	var tests = []struct {
+		description string
+		markers     map[string]string
+		noHeaders   bool
+		expected    []string
+	}{
+		{
+			"no markers",
+			nil,
+			false,
+			[]string{
+				"| NAME | %USED | IMAGE NAME |",
+				"| --- | ---: | --- |",
+				"| node1 | 30% | nginx:1.17 |",
+				`| node2 | N/A | app:v1 \| odd |`,
+				"",
+			},
+		},
+		{
+			"markers",
+			map[string]string{"warn": ":warning:"},
+			false,
+			[]string{
+				"| NAME | %USED | IMAGE NAME |",
+				"| --- | ---: | --- |",
+				"| node1 | :warning: 30% | nginx:1.17 |",
+				`| node2 | N/A | app:v1 \| odd |`,
+				"",
+			},
+		},
+		{
+			"header is always printed",
+			nil,
+			true,
+			[]string{
+				"| NAME | %USED | IMAGE NAME |",
+				"| --- | ---: | --- |",
+				"| node1 | 30% | nginx:1.17 |",
+				`| node2 | N/A | app:v1 \| odd |`,
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			table := NewOutputTable(buffer)
+			table.Format = Markdown
+			table.Markers = test.markers
+			table.NoHeaders = test.noHeaders
+			table.AddColumns([]Column{{Name: "NAME"}, {Name: "%USED", Type: Percent}, {Name: "IMAGE NAME"}})
+
+			// rows are streamed in one table
+			table.AddCells([]Cell{{Text: "node1"}, {Text: yellow("30%"), Value: 30, Status: "warn"}, {Text: "nginx:1.17"}})
+			table.Print()
+			table.AddCells([]Cell{{Text: "node2"}, {Text: "N/A"}, {Text: "app:v1 | odd"}})
+			table.Print()
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
 }
@@ -84,6 +84,12 @@ func SetPercentageColor(s *string, p, warn, crit int64) {
 	*s = red(*s)
 }
 
+// RenderImageTag returns image name whose tag is colorized
+func RenderImageTag(image string) string {
+	ColorImageTag(&image)
+	return image
+}
+
 // ColorImageTag is colorize image tag
 func ColorImageTag(image *string) {
 
@@ -110,3 +116,127 @@ func GetImageUsage(images []v1.ContainerImage) (int64, int) {
 	}
 	return s, len(images)
 }
+
+// NormalizeImageName returns fully qualified image name
+// e.g. "nginx" -> "docker.io/library/nginx:latest"
+func NormalizeImageName(name string) string {
+
+	// imageID of container status has runtime prefix like "docker-pullable://"
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+
+	// add default tag
+	if !strings.Contains(name, "@") {
+		if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+			name += ":latest"
+		}
+	}
+
+	// add default registry
+	s := strings.SplitN(name, "/", 2)
+	if len(s) == 1 {
+		return "docker.io/library/" + name
+	}
+	if !strings.ContainsAny(s[0], ".:") && s[0] != "localhost" {
+		return "docker.io/" + name
+	}
+
+	// official images on docker hub live under "library/"
+	if s[0] == "docker.io" || s[0] == "index.docker.io" {
+		if !strings.Contains(s[1], "/") {
+			return "docker.io/library/" + s[1]
+		}
+		return "docker.io/" + s[1]
+	}
+
+	return name
+}
+
+// FindImage returns image on node which matches with name
+func FindImage(images []v1.ContainerImage, name string) (v1.ContainerImage, bool) {
+
+	i := FindImageIndex(images, name)
+	if i < 0 {
+		return v1.ContainerImage{}, false
+	}
+	return images[i], true
+}
+
+// FindImageIndex returns index of image on node which matches with name
+// It returns -1 if image is not found.
+func FindImageIndex(images []v1.ContainerImage, name string) int {
+
+	n := NormalizeImageName(name)
+	for i, image := range images {
+		for _, in := range image.Names {
+			if NormalizeImageName(in) == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// GetImageName returns name of image to print
+// The first name is usually digest, so the second one is preferred.
+func GetImageName(image v1.ContainerImage) string {
+
+	if len(image.Names) == 0 {
+		return "<none>"
+	}
+
+	if len(image.Names) > 1 {
+		return image.Names[1]
+	}
+	return image.Names[0]
+}
+
+// GetPodImages returns unique image names used by containers in pod
+func GetPodImages(pod v1.Pod) []string {
+
+	containers := append([]v1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	images := []string{}
+	seen := map[string]bool{}
+	for _, c := range containers {
+		if seen[c.Image] {
+			continue
+		}
+		seen[c.Image] = true
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// ImageIDs is image ids reported by kubelet keyed by normalized image names
+// Container runtimes (e.g. containerd) report normalized names in status,
+// so names are normalized to find ids of images in pod spec.
+type ImageIDs map[string]string
+
+// Get returns image id of image name or empty if it is unknown
+func (ids ImageIDs) Get(name string) string {
+	return ids[NormalizeImageName(name)]
+}
+
+// GetPodImageIDs returns image ids of containers of pod reported by kubelet
+func GetPodImageIDs(pod v1.Pod) ImageIDs {
+
+	statuses := append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+	ids := ImageIDs{}
+	for _, s := range statuses {
+		if s.ImageID != "" {
+			ids[NormalizeImageName(s.Image)] = s.ImageID
+		}
+	}
+	return ids
+}
+
+// GetImageRegistry returns registry host of image
+// e.g. "nginx" -> "docker.io"
+func GetImageRegistry(name string) string {
+	return strings.SplitN(NormalizeImageName(name), "/", 2)[0]
+}
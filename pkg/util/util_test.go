@@ -1,6 +1,7 @@
 package util
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -201,3 +202,223 @@ func TestGetImageUsage(t *testing.T) {
 	}
 
 }
+
+func TestNormalizeImageName(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		image       string
+		expected    string
+	}{
+		{"short name", "nginx", "docker.io/library/nginx:latest"},
+		{"short name with tag", "nginx:1.17", "docker.io/library/nginx:1.17"},
+		{"docker hub user", "foo/bar:v1", "docker.io/foo/bar:v1"},
+		{"explicit docker hub", "docker.io/nginx:1.17", "docker.io/library/nginx:1.17"},
+		{"explicit docker hub index", "index.docker.io/nginx", "docker.io/library/nginx:latest"},
+		{"explicit docker hub user", "docker.io/foo/bar:v1", "docker.io/foo/bar:v1"},
+		{"registry", "k8s.gcr.io/pause:3.1", "k8s.gcr.io/pause:3.1"},
+		{"registry with port", "abc:5000/def", "abc:5000/def:latest"},
+		{"digest", "nginx@sha256:abcd", "docker.io/library/nginx@sha256:abcd"},
+		{"image id", "docker-pullable://nginx@sha256:abcd", "docker.io/library/nginx@sha256:abcd"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := NormalizeImageName(test.image)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestFindImage(t *testing.T) {
+
+	images := []v1.ContainerImage{
+		{Names: []string{"nginx@sha256:abcd", "nginx:1.17"}, SizeBytes: 1},
+		{Names: []string{"k8s.gcr.io/pause:3.1"}, SizeBytes: 10},
+	}
+
+	var tests = []struct {
+		description string
+		image       string
+		found       bool
+		expected    int64
+	}{
+		{"short name", "docker.io/library/nginx:1.17", true, 1},
+		{"digest", "docker-pullable://nginx@sha256:abcd", true, 1},
+		{"explicit docker hub", "docker.io/nginx:1.17", true, 1},
+		{"registry", "k8s.gcr.io/pause:3.1", true, 10},
+		{"not found", "nginx:latest", false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual, found := FindImage(images, test.image)
+			if found != test.found || actual.SizeBytes != test.expected {
+				t.Errorf(
+					"[%s] expected(%t, %d) differ (got: %t, %d)",
+					test.description,
+					test.found,
+					test.expected,
+					found,
+					actual.SizeBytes,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPodImages(t *testing.T) {
+
+	pod := v1.Pod{
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{
+				{Name: "init", Image: "busybox"},
+			},
+			Containers: []v1.Container{
+				{Name: "c1", Image: "nginx"},
+				{Name: "c2", Image: "busybox"},
+			},
+		},
+	}
+
+	expected := []string{"busybox", "nginx"}
+	actual := GetPodImages(pod)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+	}
+}
+
+func TestGetPodImageIDs(t *testing.T) {
+
+	pod := v1.Pod{
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Image: "busybox:latest", ImageID: "docker-pullable://busybox@sha256:1234"},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Image: "nginx:latest", ImageID: "docker-pullable://nginx@sha256:abcd"},
+				{Image: "pending:latest", ImageID: ""},
+				// containerd reports normalized name
+				{Image: "docker.io/library/redis:5", ImageID: "sha256:5678"},
+			},
+		},
+	}
+
+	expected := ImageIDs{
+		"docker.io/library/busybox:latest": "docker-pullable://busybox@sha256:1234",
+		"docker.io/library/nginx:latest":   "docker-pullable://nginx@sha256:abcd",
+		"docker.io/library/redis:5":        "sha256:5678",
+	}
+	actual := GetPodImageIDs(pod)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+	}
+
+	// ids are found by names in pod spec
+	for name, id := range map[string]string{"nginx": "docker-pullable://nginx@sha256:abcd", "redis:5": "sha256:5678", "pending": ""} {
+		if actual.Get(name) != id {
+			t.Errorf("[%s] expected(%s) differ (got: %s)", name, id, actual.Get(name))
+		}
+	}
+}
+
+func TestGetImageRegistry(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		image       string
+		expected    string
+	}{
+		{"docker hub", "nginx:1.17", "docker.io"},
+		{"registry", "k8s.gcr.io/pause:3.1", "k8s.gcr.io"},
+		{"registry with port", "abc:5000/def", "abc:5000"},
+		{"localhost", "localhost/foo", "localhost"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetImageRegistry(test.image)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestFindImageIndex(t *testing.T) {
+
+	images := []v1.ContainerImage{
+		{Names: []string{"nginx@sha256:abcd", "nginx:1.17"}, SizeBytes: 1},
+		{Names: []string{"k8s.gcr.io/pause:3.1"}, SizeBytes: 10},
+	}
+
+	var tests = []struct {
+		description string
+		image       string
+		expected    int
+	}{
+		{"first", "nginx:1.17", 0},
+		{"second", "k8s.gcr.io/pause:3.1", 1},
+		{"not found", "nginx", -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := FindImageIndex(images, test.image)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%d) differ (got: %d)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestGetImageName(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		names       []string
+		expected    string
+	}{
+		{"no names", []string{}, "<none>"},
+		{"one name", []string{"image1"}, "image1"},
+		{"two names", []string{"image1@sha256:abcd", "image1:v1"}, "image1:v1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetImageName(v1.ContainerImage{Names: test.names})
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}